@@ -0,0 +1,216 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+)
+
+// ConversionDataAnnotation holds the v1beta1 spec and status of a resource read as v1alpha1, so that fields
+// v1alpha1 can't represent survive a round trip through this version.
+const ConversionDataAnnotation = "lavinmq.cloudamqp.com/conversion-data"
+
+// conversionData is the part of the hub stored in the ConversionDataAnnotation.
+type conversionData struct {
+	Spec   v1beta1.LavinMQSpec   `json:"spec"`
+	Status v1beta1.LavinMQStatus `json:"status"`
+}
+
+var _ conversion.Convertible = &LavinMQ{}
+
+// ConvertTo converts this LavinMQ to the hub version (v1beta1).
+func (src *LavinMQ) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.LavinMQ)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 LavinMQ but got %T", dstRaw)
+	}
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	// Start from the fields only v1beta1 has, everything v1alpha1 represents is set below.
+	if data, ok := dst.Annotations[ConversionDataAnnotation]; ok {
+		restored := conversionData{}
+		if err := json.Unmarshal([]byte(data), &restored); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", ConversionDataAnnotation, err)
+		}
+		dst.Spec = restored.Spec
+		dst.Status = restored.Status
+		delete(dst.Annotations, ConversionDataAnnotation)
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+	}
+
+	spec := src.Spec.DeepCopy()
+	dst.Spec.Image = spec.Image
+	dst.Spec.Replicas = spec.Replicas
+	dst.Spec.NodeSelector = spec.NodeSelector
+	dst.Spec.Affinity = spec.Affinity
+	dst.Spec.Resources = spec.Resources
+	dst.Spec.Persistence.DataVolumeClaimSpec = spec.DataVolumeClaimSpec
+	dst.Spec.Clustering.EtcdEndpoints = spec.EtcdEndpoints
+	dst.Spec.Clustering.MaxUnsyncedActions = spec.Config.Clustering.MaxUnsyncedActions
+	dst.Spec.TLS = nil
+	if spec.TlsSecret != nil {
+		// The secret is mounted in the pods, it's always read from the namespace of the LavinMQ.
+		dst.Spec.TLS = &v1beta1.TLSSpec{SecretName: spec.TlsSecret.Name}
+	}
+	convertConfigTo(&spec.Config, &dst.Spec.Config)
+
+	status := src.Status.DeepCopy()
+	dst.Status.Conditions = status.Conditions
+	dst.Status.LastDriftCorrection = (*v1beta1.DriftCorrection)(status.LastDriftCorrection)
+
+	return nil
+}
+
+// ConvertFrom converts from the hub version (v1beta1) to this version.
+func (dst *LavinMQ) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.LavinMQ)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 LavinMQ but got %T", srcRaw)
+	}
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	data, err := json.Marshal(conversionData{Spec: src.Spec, Status: src.Status})
+	if err != nil {
+		return fmt.Errorf("failed to store %s: %w", ConversionDataAnnotation, err)
+	}
+	if dst.Annotations == nil {
+		dst.Annotations = map[string]string{}
+	}
+	dst.Annotations[ConversionDataAnnotation] = string(data)
+
+	spec := src.Spec.DeepCopy()
+	dst.Spec = LavinMQSpec{
+		Image:               spec.Image,
+		Replicas:            spec.Replicas,
+		NodeSelector:        spec.NodeSelector,
+		Affinity:            spec.Affinity,
+		Resources:           spec.Resources,
+		DataVolumeClaimSpec: spec.Persistence.DataVolumeClaimSpec,
+		EtcdEndpoints:       spec.Clustering.EtcdEndpoints,
+		Config: LavinMQConfig{
+			Main:       convertMainConfigFrom(&spec.Config.Main),
+			Mgmt:       convertMgmtConfigFrom(&spec.Config.Mgmt),
+			Amqp:       convertAmqpConfigFrom(&spec.Config.Amqp),
+			Mqtt:       convertMqttConfigFrom(&spec.Config.Mqtt),
+			Clustering: ClusteringConfig{MaxUnsyncedActions: spec.Clustering.MaxUnsyncedActions},
+		},
+	}
+	if spec.TLS != nil {
+		dst.Spec.TlsSecret = &corev1.SecretReference{Name: spec.TLS.SecretName}
+	}
+
+	status := src.Status.DeepCopy()
+	dst.Status = LavinMQStatus{
+		Conditions:          status.Conditions,
+		LastDriftCorrection: (*DriftCorrection)(status.LastDriftCorrection),
+	}
+
+	return nil
+}
+
+// convertConfigTo sets the config fields v1alpha1 has on dst, the fields only v1beta1 has are left as restored
+// from the ConversionDataAnnotation.
+func convertConfigTo(src *LavinMQConfig, dst *v1beta1.LavinMQConfig) {
+	dst.Main.ConsumerTimeout = src.Main.ConsumerTimeout
+	dst.Main.DefaultConsumerPrefetch = src.Main.DefaultConsumerPrefetch
+	dst.Main.DefaultPassword = src.Main.DefaultPassword
+	dst.Main.DefaultUser = src.Main.DefaultUser
+	dst.Main.FreeDiskMin = src.Main.FreeDiskMin
+	dst.Main.FreeDiskWarn = src.Main.FreeDiskWarn
+	dst.Main.LogExchange = src.Main.LogExchange
+	dst.Main.LogLevel = src.Main.LogLevel
+	dst.Main.MaxDeletedDefinitions = src.Main.MaxDeletedDefinitions
+	dst.Main.SegmentSize = src.Main.SegmentSize
+	dst.Main.SetTimestamp = src.Main.SetTimestamp
+	dst.Main.SocketBufferSize = src.Main.SocketBufferSize
+	dst.Main.StatsInterval = src.Main.StatsInterval
+	dst.Main.StatsLogSize = src.Main.StatsLogSize
+	dst.Main.TcpKeepalive = src.Main.TcpKeepalive
+	dst.Main.TcpNodelay = src.Main.TcpNodelay
+	dst.Main.TlsCiphers = src.Main.TlsCiphers
+	dst.Main.TlsMinVersion = src.Main.TlsMinVersion
+	dst.Mgmt.Port = src.Mgmt.Port
+	dst.Mgmt.TlsPort = src.Mgmt.TlsPort
+	dst.Amqp.ChannelMax = src.Amqp.ChannelMax
+	dst.Amqp.FrameMax = src.Amqp.FrameMax
+	dst.Amqp.Heartbeat = src.Amqp.Heartbeat
+	dst.Amqp.MaxMessageSize = src.Amqp.MaxMessageSize
+	dst.Amqp.Port = src.Amqp.Port
+	dst.Amqp.TlsPort = src.Amqp.TlsPort
+	dst.Mqtt.MaxInflightMessages = src.Mqtt.MaxInflightMessages
+	dst.Mqtt.Port = src.Mqtt.Port
+	dst.Mqtt.TlsPort = src.Mqtt.TlsPort
+}
+
+func convertMainConfigFrom(src *v1beta1.MainConfig) MainConfig {
+	return MainConfig{
+		ConsumerTimeout:         src.ConsumerTimeout,
+		DefaultConsumerPrefetch: src.DefaultConsumerPrefetch,
+		DefaultPassword:         src.DefaultPassword,
+		DefaultUser:             src.DefaultUser,
+		FreeDiskMin:             src.FreeDiskMin,
+		FreeDiskWarn:            src.FreeDiskWarn,
+		LogExchange:             src.LogExchange,
+		LogLevel:                src.LogLevel,
+		MaxDeletedDefinitions:   src.MaxDeletedDefinitions,
+		SegmentSize:             src.SegmentSize,
+		SetTimestamp:            src.SetTimestamp,
+		SocketBufferSize:        src.SocketBufferSize,
+		StatsInterval:           src.StatsInterval,
+		StatsLogSize:            src.StatsLogSize,
+		TcpKeepalive:            src.TcpKeepalive,
+		TcpNodelay:              src.TcpNodelay,
+		TlsCiphers:              src.TlsCiphers,
+		TlsMinVersion:           src.TlsMinVersion,
+	}
+}
+
+func convertMgmtConfigFrom(src *v1beta1.MgmtConfig) MgmtConfig {
+	return MgmtConfig{
+		Port:    src.Port,
+		TlsPort: src.TlsPort,
+	}
+}
+
+func convertAmqpConfigFrom(src *v1beta1.AmqpConfig) AmqpConfig {
+	return AmqpConfig{
+		ChannelMax:     src.ChannelMax,
+		FrameMax:       src.FrameMax,
+		Heartbeat:      src.Heartbeat,
+		MaxMessageSize: src.MaxMessageSize,
+		Port:           src.Port,
+		TlsPort:        src.TlsPort,
+	}
+}
+
+func convertMqttConfigFrom(src *v1beta1.MqttConfig) MqttConfig {
+	return MqttConfig{
+		MaxInflightMessages: src.MaxInflightMessages,
+		Port:                src.Port,
+		TlsPort:             src.TlsPort,
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+)
+
+func alphaLavinMQ() *LavinMQ {
+	storageClass := "standard"
+	return &LavinMQ{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "lavinmq",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "lavinmq"},
+			Annotations: map[string]string{"note": "kept"},
+		},
+		Spec: LavinMQSpec{
+			Image:        "cloudamqp/lavinmq:2.4.1",
+			Replicas:     3,
+			NodeSelector: map[string]string{"disk": "ssd"},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+			},
+			DataVolumeClaimSpec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &storageClass,
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+				},
+			},
+			EtcdEndpoints: []string{"etcd-0:2379", "etcd-1:2379"},
+			TlsSecret:     &corev1.SecretReference{Name: "lavinmq-tls"},
+			Config: LavinMQConfig{
+				Main:       MainConfig{LogLevel: "debug", ConsumerTimeout: 20000},
+				Mgmt:       MgmtConfig{Port: 15672, TlsPort: 15671},
+				Amqp:       AmqpConfig{Port: 5672, TlsPort: 5671, ChannelMax: 100},
+				Mqtt:       MqttConfig{Port: -1},
+				Clustering: ClusteringConfig{MaxUnsyncedActions: 8192},
+			},
+		},
+		Status: LavinMQStatus{
+			Conditions: []metav1.Condition{{Type: "Available", Status: metav1.ConditionTrue, Reason: "Reconciled"}},
+		},
+	}
+}
+
+func TestConvertToHub(t *testing.T) {
+	t.Parallel()
+	hub := &v1beta1.LavinMQ{}
+	assert.NoError(t, alphaLavinMQ().ConvertTo(hub))
+
+	assert.Equal(t, "lavinmq", hub.Name)
+	assert.Equal(t, map[string]string{"note": "kept"}, hub.Annotations)
+	assert.Equal(t, int32(3), hub.Spec.Replicas)
+	assert.Equal(t, "standard", *hub.Spec.Persistence.DataVolumeClaimSpec.StorageClassName)
+	assert.Equal(t, []string{"etcd-0:2379", "etcd-1:2379"}, hub.Spec.Clustering.EtcdEndpoints)
+	assert.Equal(t, uint64(8192), hub.Spec.Clustering.MaxUnsyncedActions)
+	assert.Equal(t, &v1beta1.TLSSpec{SecretName: "lavinmq-tls"}, hub.Spec.TLS)
+	assert.Equal(t, "debug", hub.Spec.Config.Main.LogLevel)
+	assert.Equal(t, int32(5671), hub.Spec.Config.Amqp.TlsPort)
+	assert.Len(t, hub.Status.Conditions, 1)
+}
+
+func TestRoundTripSpokeHubSpoke(t *testing.T) {
+	t.Parallel()
+	original := alphaLavinMQ()
+	hub := &v1beta1.LavinMQ{}
+	assert.NoError(t, original.ConvertTo(hub))
+
+	restored := &LavinMQ{}
+	assert.NoError(t, restored.ConvertFrom(hub))
+
+	assert.Equal(t, original.Spec, restored.Spec)
+	assert.Equal(t, original.Status, restored.Status)
+	assert.Equal(t, original.Labels, restored.Labels)
+	assert.Equal(t, "kept", restored.Annotations["note"])
+}
+
+func TestRoundTripHubSpokeHub(t *testing.T) {
+	t.Parallel()
+	original := &v1beta1.LavinMQ{}
+	assert.NoError(t, alphaLavinMQ().ConvertTo(original))
+	// Fields only v1beta1 has
+	original.Spec.Service.Annotations = map[string]string{"service.beta.kubernetes.io/aws-load-balancer-internal": "true"}
+	original.Spec.Config.Main.MaxConnections = 1000
+	original.Spec.Config.Amqp.UnixPath = "/run/lavinmq/amqp.sock"
+
+	spoke := &LavinMQ{}
+	assert.NoError(t, spoke.ConvertFrom(original))
+	assert.Contains(t, spoke.Annotations, ConversionDataAnnotation)
+
+	restored := &v1beta1.LavinMQ{}
+	assert.NoError(t, spoke.ConvertTo(restored))
+
+	assert.Equal(t, original.Spec, restored.Spec)
+	assert.Equal(t, original.Status, restored.Status)
+	assert.Equal(t, original.ObjectMeta, restored.ObjectMeta)
+}
+
+func TestSpokeChangesWinOverConversionData(t *testing.T) {
+	t.Parallel()
+	hub := &v1beta1.LavinMQ{}
+	assert.NoError(t, alphaLavinMQ().ConvertTo(hub))
+	hub.Spec.Service.Annotations = map[string]string{"team": "messaging"}
+	hub.Spec.Config.Mqtt.DefaultVhost = "mqtt"
+
+	spoke := &LavinMQ{}
+	assert.NoError(t, spoke.ConvertFrom(hub))
+	spoke.Spec.Image = "cloudamqp/lavinmq:2.5.0"
+	spoke.Spec.TlsSecret = nil
+	spoke.Spec.EtcdEndpoints = nil
+	spoke.Spec.Config.Mqtt.MaxInflightMessages = 100
+
+	updated := &v1beta1.LavinMQ{}
+	assert.NoError(t, spoke.ConvertTo(updated))
+
+	assert.Equal(t, "cloudamqp/lavinmq:2.5.0", updated.Spec.Image)
+	assert.Nil(t, updated.Spec.TLS)
+	assert.Empty(t, updated.Spec.Clustering.EtcdEndpoints)
+	assert.Equal(t, map[string]string{"team": "messaging"}, updated.Spec.Service.Annotations)
+	assert.Equal(t, uint64(100), updated.Spec.Config.Mqtt.MaxInflightMessages)
+	assert.Equal(t, "mqtt", updated.Spec.Config.Mqtt.DefaultVhost)
+}
@@ -43,6 +43,10 @@ type LavinMQSpec struct {
 	// +optional
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 
+	// Scheduling constraints of the pods, defaults to spreading the pods over different nodes.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
 	// +optional
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 
@@ -56,6 +60,7 @@ type LavinMQSpec struct {
 	// +optional
 	TlsSecret *corev1.SecretReference `json:"tlsSecret,omitempty"`
 
+	// +kubebuilder:default={}
 	// +optional
 	Config LavinMQConfig `json:"config,omitempty"`
 }
@@ -119,7 +124,7 @@ type MainConfig struct {
 	// +optional
 	StatsLogSize uint64 `json:"stats_log_size,omitempty"`
 
-	// TCP keepalive settings as a tuple {idle, interval, probes/count}.
+	// TCP keepalive settings as idle:interval:count, e.g. 60:10:3, or false to disable.
 	// +optional
 	TcpKeepalive string `json:"tcp_keepalive,omitempty"`
 
@@ -208,10 +213,15 @@ type ClusteringConfig struct {
 }
 
 type LavinMQConfig struct {
-	Main       MainConfig       `json:"main,omitempty"`
-	Mgmt       MgmtConfig       `json:"mgmt,omitempty"`
-	Amqp       AmqpConfig       `json:"amqp,omitempty"`
-	Mqtt       MqttConfig       `json:"mqtt,omitempty"`
+	// +kubebuilder:default={}
+	Main MainConfig `json:"main,omitempty"`
+	// +kubebuilder:default={}
+	Mgmt MgmtConfig `json:"mgmt,omitempty"`
+	// +kubebuilder:default={}
+	Amqp AmqpConfig `json:"amqp,omitempty"`
+	// +kubebuilder:default={}
+	Mqtt MqttConfig `json:"mqtt,omitempty"`
+	// +kubebuilder:default={}
 	Clustering ClusteringConfig `json:"clustering,omitempty"`
 }
 
@@ -223,10 +233,27 @@ type LavinMQStatus struct {
 	// Conditions store the status conditions of the LavinMQ instances
 	// +lavinmq-operator:csv:customresourcedefinitions:type=status
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// LastDriftCorrection is the latest change to an owned resource made outside of the operator that got reverted
+	// +optional
+	LastDriftCorrection *DriftCorrection `json:"lastDriftCorrection,omitempty"`
+}
+
+// DriftCorrection describes fields of an owned resource changed by someone else than the operator
+type DriftCorrection struct {
+	// Resource is the kind and name of the corrected resource, e.g. StatefulSet/lavinmq
+	Resource string `json:"resource"`
+	// Fields that were reverted
+	Fields []string `json:"fields"`
+	// Managers are the field managers that changed the fields, e.g. kubectl-edit
+	Managers []string `json:"managers"`
+	// Time the drift was corrected
+	Time metav1.Time `json:"time"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:deprecatedversion:warning="cloudamqp.com/v1alpha1 LavinMQ is deprecated, use cloudamqp.com/v1beta1"
 
 // LavinMQ is the Schema for the lavinmqs API
 type LavinMQ struct {
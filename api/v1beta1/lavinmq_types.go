@@ -0,0 +1,581 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotations on the LavinMQ resource that control the operator.
+const (
+	// ForceAnnotation set to "true" allows risky changes to the spec, like disabling a listener or removing etcd
+	// from a cluster, that are rejected otherwise.
+	ForceAnnotation = "lavinmq.cloudamqp.com/force"
+	// PausedAnnotation set to "true" stops the operator from reconciling the instance, so manual changes to the
+	// owned resources aren't reverted.
+	PausedAnnotation = "lavinmq.cloudamqp.com/paused"
+	// RestartedAtAnnotation triggers a rolling restart of the pods whenever its value, typically a timestamp,
+	// changes.
+	RestartedAtAnnotation = "lavinmq.cloudamqp.com/restartedAt"
+)
+
+// The rules repeat the replica checks of the validating webhook, which isn't called for updates through the
+// scale subresource.
+// +kubebuilder:validation:XValidation:rule="!has(self.replicas) || self.replicas <= 1 || (has(self.clustering) && has(self.clustering.etcdEndpoints) && size(self.clustering.etcdEndpoints) > 0)",message="a provided etcd cluster is required for replication"
+// +kubebuilder:validation:XValidation:rule="!has(self.replicas) || self.replicas <= 1 || (has(oldSelf.replicas) && oldSelf.replicas > 1) || (has(oldSelf.clustering) && has(oldSelf.clustering.etcdEndpoints) && size(oldSelf.clustering.etcdEndpoints) > 0)",message="in order to safely transition without message loss from single to multi node, first update to run the single node with etcd cluster, then update to multi node"
+
+// LavinMQSpec defines the desired state of LavinMQ
+type LavinMQSpec struct {
+	// +kubebuilder:default="cloudamqp/lavinmq:2.4.1"
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=3
+	// +kubebuilder:default=1
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Selector used to select the nodes on which the pods will be scheduled.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Scheduling constraints of the pods, defaults to spreading the pods over different nodes.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Labels added to the pods, on top of the labels of the LavinMQ. Changing them rolls the pods.
+	// +optional
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+
+	// Annotations added to the pods. Changing them rolls the pods.
+	// +optional
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+
+	// Seconds a pod gets to shut down, which the leader uses to hand over to a follower and to drain its
+	// client connections. Defaults to 60.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+
+	// Maintenance keeps the pods running as they are until it's cleared. Changes to the spec aren't rolled out,
+	// the instance isn't scaled and upgrades, restarts and storage migrations wait.
+	// +optional
+	Maintenance bool `json:"maintenance,omitempty"`
+
+	// Persistence configures the volumes holding the message data.
+	// +required
+	Persistence PersistenceSpec `json:"persistence"`
+
+	// Clustering configures replication between the nodes, required when running more than one replica.
+	// +optional
+	Clustering ClusteringSpec `json:"clustering,omitempty"`
+
+	// TLS enables the TLS listeners, using the certificate from a secret.
+	// +optional
+	TLS *TLSSpec `json:"tls,omitempty"`
+
+	// Service configures the Service in front of the pods.
+	// +optional
+	Service ServiceSpec `json:"service,omitempty"`
+
+	// Probes configures the timings of the health checks of the LavinMQ container.
+	// +optional
+	Probes ProbesSpec `json:"probes,omitempty"`
+
+	// +kubebuilder:default={}
+	// +optional
+	Config LavinMQConfig `json:"config,omitempty"`
+}
+
+type PersistenceSpec struct {
+	// Claim used for the data volume of each pod. Will override the accessmode and force it to ReadWriteOnce.
+	// +required
+	DataVolumeClaimSpec corev1.PersistentVolumeClaimSpec `json:"dataVolumeClaim"`
+
+	// RestoreFromSnapshot seeds new data volumes from the VolumeSnapshots of a ready LavinMQSnapshot.
+	// Existing volumes are not affected.
+	// +optional
+	RestoreFromSnapshot *RestoreFromSnapshotSpec `json:"restoreFromSnapshot,omitempty"`
+
+	// Volumes mounted in addition to the data volume, e.g. to keep parts of /var/lib/lavinmq on faster storage.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Volumes []VolumeSpec `json:"volumes,omitempty"`
+}
+
+// VolumeSpec is a volume of each pod, backed by either a PersistentVolumeClaim or an emptyDir.
+// +kubebuilder:validation:XValidation:rule="has(self.volumeClaim) != has(self.emptyDir)",message="exactly one of volumeClaim and emptyDir must be set"
+type VolumeSpec struct {
+	// Name of the volume, the PVCs of a volume claim are named <name>-<lavinmq>-<ordinal>.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	// +required
+	Name string `json:"name"`
+
+	// Path in the LavinMQ container the volume is mounted at.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +required
+	MountPath string `json:"mountPath"`
+
+	// Claim of the PersistentVolumeClaim created for each pod. Will override the accessmode and force it to ReadWriteOnce.
+	// +optional
+	VolumeClaimSpec *corev1.PersistentVolumeClaimSpec `json:"volumeClaim,omitempty"`
+
+	// EmptyDir is a scratch volume that is removed with the pod, set medium to Memory for a tmpfs.
+	// +optional
+	EmptyDir *corev1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+}
+
+type RestoreFromSnapshotSpec struct {
+	// Name of the LavinMQSnapshot, in the same namespace, to restore from.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Name string `json:"name"`
+}
+
+type ClusteringSpec struct {
+	// Endpoints of the etcd cluster used for leader election, enables clustering when set.
+	// +optional
+	EtcdEndpoints []string `json:"etcdEndpoints,omitempty"`
+
+	// Maximum number of unsynced actions allowed in the cluster.
+	// +optional
+	MaxUnsyncedActions uint64 `json:"max_unsynced_actions,omitempty"`
+
+	// Shell command run on a node when it becomes the leader.
+	// +optional
+	OnLeaderElected string `json:"on_leader_elected,omitempty"`
+
+	// Shell command run on a node when it loses the leadership.
+	// +optional
+	OnLeaderLost string `json:"on_leader_lost,omitempty"`
+}
+
+// Enabled reports whether the nodes replicate over etcd.
+func (c *ClusteringSpec) Enabled() bool {
+	return len(c.EtcdEndpoints) > 0
+}
+
+type TLSSpec struct {
+	// Name of the secret, in the namespace of the LavinMQ, holding tls.crt and tls.key.
+	// +required
+	SecretName string `json:"secretName"`
+}
+
+type ServiceSpec struct {
+	// Annotations added to the Service.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ProbesSpec struct {
+	// Startup probe, the other probes start once it succeeds. Defaults to checking every 10s, 30 times.
+	// +optional
+	Startup *ProbeTimings `json:"startup,omitempty"`
+
+	// Liveness probe, the container is restarted when it fails. Defaults to checking every 10s.
+	// +optional
+	Liveness *ProbeTimings `json:"liveness,omitempty"`
+
+	// Readiness probe, the pod gets no client traffic while it fails. Defaults to checking every 10s after 5s.
+	// +optional
+	Readiness *ProbeTimings `json:"readiness,omitempty"`
+}
+
+// ProbeTimings overrides the timings of a probe, unset fields keep their default.
+type ProbeTimings struct {
+	// Seconds after the container started before the probe is run.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	InitialDelaySeconds *int32 `json:"initialDelaySeconds,omitempty"`
+
+	// How often, in seconds, to run the probe.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PeriodSeconds *int32 `json:"periodSeconds,omitempty"`
+
+	// Seconds after which the probe times out.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// Consecutive failures before the probe is considered failed.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+}
+
+type MainConfig struct {
+	// Authentication backends, in the order they're tried. Defaults to local users only.
+	// +optional
+	AuthBackends []AuthBackend `json:"auth_backends,omitempty"`
+
+	// The timeout for consumers in milliseconds.
+	// +optional
+	ConsumerTimeout uint64 `json:"consumer_timeout,omitempty"`
+
+	// How often in milliseconds consumers are checked for exceeding the consumer timeout.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ConsumerTimeoutLoopInterval uint64 `json:"consumer_timeout_loop_interval,omitempty"`
+
+	// Locks the data directory so a second LavinMQ process can't use it at the same time. Defaults to true.
+	// +optional
+	DataDirLock *bool `json:"data_dir_lock,omitempty"`
+
+	// Default prefetch value for consumers if not set by the consumer.
+	// +optional
+	DefaultConsumerPrefetch uint64 `json:"default_consumer_prefetch,omitempty"`
+
+	// Hashed password for the default user.
+	// Use lavinmqctl hash_password or /api/auth/hash_password to generate the password hash.
+	// +optional
+	DefaultPassword string `json:"default_password,omitempty"`
+
+	// The default user.
+	// +optional
+	DefaultUser string `json:"default_user,omitempty"`
+
+	// Only allow the default user to connect from localhost. Defaults to true.
+	// +optional
+	DefaultUserOnlyLoopback *bool `json:"default_user_only_loopback,omitempty"`
+
+	// The minimum value of free disk space in bytes before LavinMQ starts to control flow.
+	// +optional
+	FreeDiskMin uint64 `json:"free_disk_min,omitempty"`
+
+	// The minimum value of free disk space in bytes before LavinMQ warns about low disk space.
+	// +optional
+	FreeDiskWarn uint64 `json:"free_disk_warn,omitempty"`
+
+	// Enables the log exchange.
+	// +optional
+	LogExchange bool `json:"log_exchange,omitempty"`
+
+	// Controls how detailed the log should be.
+	// The level can be one of: none, fatal, error, warn, info, debug.
+	// +optional
+	LogLevel string `json:"log_level,omitempty"`
+
+	// Maximum number of client connections per node, unlimited if unset.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConnections uint64 `json:"max_connections,omitempty"`
+
+	// The number of deleted queues, unbinds, etc., that compacts the definitions file.
+	// +optional
+	MaxDeletedDefinitions uint64 `json:"max_deleted_definitions,omitempty"`
+
+	// The size of segment files in bytes.
+	// +optional
+	SegmentSize uint64 `json:"segment_size,omitempty"`
+
+	// Enables setting the timestamp property in msg headers.
+	// +optional
+	SetTimestamp bool `json:"set_timestamp,omitempty"`
+
+	// The socket buffer size in bytes.
+	// +optional
+	SocketBufferSize uint64 `json:"socket_buffer_size,omitempty"`
+
+	// Statistics collection interval in milliseconds.
+	// +optional
+	StatsInterval uint64 `json:"stats_interval,omitempty"`
+
+	// Number of entries in the statistics log file before the oldest entry is removed.
+	// +optional
+	StatsLogSize uint64 `json:"stats_log_size,omitempty"`
+
+	// Size in bytes of the kernel receive buffer of client sockets, the kernel default is used if unset.
+	// +optional
+	TcpRecvBufferSize uint64 `json:"tcp_recv_buffer_size,omitempty"`
+
+	// Size in bytes of the kernel send buffer of client sockets, the kernel default is used if unset.
+	// +optional
+	TcpSendBufferSize uint64 `json:"tcp_send_buffer_size,omitempty"`
+
+	// TCP keepalive settings as idle:interval:count, e.g. 60:10:3, or false to disable.
+	// +optional
+	TcpKeepalive string `json:"tcp_keepalive,omitempty"`
+
+	// Setting for disabling Nagle's algorithm and sending the data as soon as it's available.
+	// +optional
+	TcpNodelay bool `json:"tcp_nodelay,omitempty"`
+
+	// Specifies the TLS ciphers to use.
+	// +optional
+	TlsCiphers string `json:"tls_ciphers,omitempty"`
+
+	// Specifies the minimum TLS version to use.
+	// +optional
+	TlsMinVersion string `json:"tls_min_version,omitempty"`
+
+	// Number of bytes delivered to a consumer before yielding to other fibers.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	YieldEachDeliveredBytes uint64 `json:"yield_each_delivered_bytes,omitempty"`
+
+	// Number of bytes received from a client before yielding to other fibers.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	YieldEachReceivedBytes uint64 `json:"yield_each_received_bytes,omitempty"`
+}
+
+// AuthBackend is a source LavinMQ authenticates users against.
+// +kubebuilder:validation:Enum=local;oauth
+type AuthBackend string
+
+type MgmtConfig struct {
+	// Port for the HTTP management interface. Set to -1 to disable.
+	// +kubebuilder:validation:Minimum=-1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=15672
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// Port for the HTTPS management interface.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	TlsPort int32 `json:"tls_port,omitempty"`
+
+	// Path of a Unix socket the management interface listens on as well, e.g. on an emptyDir volume.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	UnixPath string `json:"unix_path,omitempty"`
+}
+
+type AmqpConfig struct {
+	// Maximum number of channels per connection.
+	// +optional
+	ChannelMax uint64 `json:"channel_max,omitempty"`
+
+	// Maximum size of an AMQP frame in bytes.
+	// +optional
+	FrameMax uint64 `json:"frame_max,omitempty"`
+
+	// Interval in seconds for AMQP heartbeats.
+	// +optional
+	Heartbeat uint64 `json:"heartbeat,omitempty"`
+
+	// Maximum size of a message in bytes.
+	// +optional
+	MaxMessageSize uint64 `json:"max_message_size,omitempty"`
+
+	// Port for the AMQP interface. Set to -1 to disable.
+	// +kubebuilder:validation:Minimum=-1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=5672
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// Expect the PROXY protocol, version 1 or 2, on the AMQP port, e.g. behind a load balancer. Disabled if unset.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=2
+	// +optional
+	TcpProxyProtocol int32 `json:"tcp_proxy_protocol,omitempty"`
+
+	// Port for the AMQPS interface.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	TlsPort int32 `json:"tls_port,omitempty"`
+
+	// Path of a Unix socket AMQP clients can connect through as well, e.g. on an emptyDir volume.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	UnixPath string `json:"unix_path,omitempty"`
+
+	// PROXY protocol version, 1 or 2, expected on the Unix socket, 0 disables it. Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=2
+	// +optional
+	UnixProxyProtocol *int32 `json:"unix_proxy_protocol,omitempty"`
+}
+
+type MqttConfig struct {
+	// Virtual host MQTT clients connect to. Defaults to /.
+	// +optional
+	DefaultVhost string `json:"default_vhost,omitempty"`
+
+	// Maximum number of in-flight messages per client.
+	// +optional
+	MaxInflightMessages uint64 `json:"max_inflight_messages,omitempty"`
+
+	// Port for the MQTT interface. Set to -1 to disable.
+	// +kubebuilder:validation:Minimum=-1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=1883
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// Port for the MQTTS interface.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	TlsPort int32 `json:"tls_port,omitempty"`
+
+	// Path of a Unix socket MQTT clients can connect through as well, e.g. on an emptyDir volume.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	UnixPath string `json:"unix_path,omitempty"`
+}
+
+type LavinMQConfig struct {
+	// +kubebuilder:default={}
+	Main MainConfig `json:"main,omitempty"`
+	// +kubebuilder:default={}
+	Mgmt MgmtConfig `json:"mgmt,omitempty"`
+	// +kubebuilder:default={}
+	Amqp AmqpConfig `json:"amqp,omitempty"`
+	// +kubebuilder:default={}
+	Mqtt MqttConfig `json:"mqtt,omitempty"`
+}
+
+// LavinMQStatus defines the observed state of LavinMQ
+type LavinMQStatus struct {
+	// Conditions store the status conditions of the LavinMQ instances
+	// +lavinmq-operator:csv:customresourcedefinitions:type=status
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// LastDriftCorrection is the latest change to an owned resource made outside of the operator that got reverted
+	// +optional
+	LastDriftCorrection *DriftCorrection `json:"lastDriftCorrection,omitempty"`
+
+	// VolumeExpansion is the progress of an ongoing expansion of the data volumes, unset when no expansion is running
+	// +optional
+	VolumeExpansion *VolumeExpansionStatus `json:"volumeExpansion,omitempty"`
+
+	// StorageMigration is the progress of moving the data volumes to a new StorageClass, unset when no migration is running
+	// +optional
+	StorageMigration *StorageMigrationStatus `json:"storageMigration,omitempty"`
+
+	// CurrentImage is the image all pods run, a failed upgrade is rolled back to it
+	// +optional
+	CurrentImage string `json:"currentImage,omitempty"`
+
+	// CurrentVersion is the LavinMQ version all pods run, unset if the image isn't tagged with a version
+	// +optional
+	CurrentVersion string `json:"currentVersion,omitempty"`
+
+	// TargetVersion is the LavinMQ version the pods are upgraded to, unset when no upgrade is running
+	// +optional
+	TargetVersion string `json:"targetVersion,omitempty"`
+
+	// RolledBackImage is the image of the last upgrade that failed and was rolled back. It's not retried until
+	// spec.image is changed.
+	// +optional
+	RolledBackImage string `json:"rolledBackImage,omitempty"`
+
+	// RestartedAt is the value of the restartedAt annotation of the last completed restart
+	// +optional
+	RestartedAt string `json:"restartedAt,omitempty"`
+
+	// RestartRequestedAt is the value of the restartedAt annotation of the restart in progress, it's completed
+	// even if the annotation is removed
+	// +optional
+	RestartRequestedAt string `json:"restartRequestedAt,omitempty"`
+
+	// RestartCompletedAt is when all pods were last restarted through the restartedAt annotation
+	// +optional
+	RestartCompletedAt *metav1.Time `json:"restartCompletedAt,omitempty"`
+
+	// Replicas is the number of pods of the StatefulSet, reported through the scale subresource
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Selector is the label selector of the pods, used by autoscalers through the scale subresource
+	// +optional
+	Selector string `json:"selector,omitempty"`
+}
+
+// VolumeExpansionStatus describes the progress of resizing the data volumes to a larger size
+type VolumeExpansionStatus struct {
+	// TargetSize is the storage request the volumes are expanded to
+	TargetSize resource.Quantity `json:"targetSize"`
+	// Resized is the number of volumes whose capacity reached the target size
+	Resized int32 `json:"resized"`
+	// Total is the number of volumes being expanded
+	Total int32 `json:"total"`
+	// FileSystemResizePending lists the PVCs where the volume is expanded but the file system is not, which
+	// happens once the volume is (re)mounted by a pod
+	// +optional
+	FileSystemResizePending []string `json:"fileSystemResizePending,omitempty"`
+	// StartTime is when the expansion started
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// DriftCorrection describes fields of an owned resource changed by someone else than the operator
+type DriftCorrection struct {
+	// Resource is the kind and name of the corrected resource, e.g. StatefulSet/lavinmq
+	Resource string `json:"resource"`
+	// Fields that were reverted
+	Fields []string `json:"fields"`
+	// Managers are the field managers that changed the fields, e.g. kubectl-edit
+	Managers []string `json:"managers"`
+	// Time the drift was corrected
+	Time metav1.Time `json:"time"`
+}
+
+// StorageMigrationStatus describes the progress of moving the data volumes, one pod at a time, to a new StorageClass
+type StorageMigrationStatus struct {
+	// StorageClassName is the StorageClass the volumes are moved to
+	StorageClassName string `json:"storageClassName"`
+	// Migrated is the number of pods whose data volume uses the new StorageClass
+	Migrated int32 `json:"migrated"`
+	// Total is the number of pods being migrated
+	Total int32 `json:"total"`
+	// CurrentPod is the pod that is being moved to a new volume and resyncing its data from the leader
+	// +optional
+	CurrentPod string `json:"currentPod,omitempty"`
+	// StartTime is when the migration started
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:storageversion
+
+// LavinMQ is the Schema for the lavinmqs API
+type LavinMQ struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   LavinMQSpec   `json:"spec,omitempty"`
+	Status LavinMQStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// LavinMQList contains a list of LavinMQ
+type LavinMQList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LavinMQ `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LavinMQ{}, &LavinMQList{})
+}
@@ -0,0 +1,307 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ClusteringPort is the port LavinMQ nodes replicate over when clustering is enabled.
+const ClusteringPort = 5679
+
+var (
+	supportedLogLevels      = []string{"none", "fatal", "error", "warn", "info", "debug"}
+	supportedTlsMinVersions = []string{"1.0", "1.1", "1.2", "1.3"}
+)
+
+// validateSpec returns all problems with the spec, independent of earlier versions of the resource.
+func validateSpec(spec *LavinMQSpec) field.ErrorList {
+	specPath := field.NewPath("spec")
+	configPath := specPath.Child("config")
+	errs := field.ErrorList{}
+
+	errs = append(errs, validatePorts(spec, configPath)...)
+	errs = append(errs, metav1validation.ValidateLabels(spec.PodLabels, specPath.Child("podLabels"))...)
+	errs = append(errs, apivalidation.ValidateAnnotations(spec.PodAnnotations, specPath.Child("podAnnotations"))...)
+	errs = append(errs, apivalidation.ValidateAnnotations(spec.Service.Annotations, specPath.Child("service", "annotations"))...)
+
+	if spec.Config.Main.LogLevel != "" && !slices.Contains(supportedLogLevels, spec.Config.Main.LogLevel) {
+		errs = append(errs, field.NotSupported(configPath.Child("main", "log_level"), spec.Config.Main.LogLevel, supportedLogLevels))
+	}
+
+	if spec.Config.Main.TcpKeepalive != "" {
+		if err := validateTcpKeepalive(spec.Config.Main.TcpKeepalive); err != nil {
+			errs = append(errs, field.Invalid(configPath.Child("main", "tcp_keepalive"), spec.Config.Main.TcpKeepalive, err.Error()))
+		}
+	}
+
+	if spec.Config.Main.TlsMinVersion != "" && !slices.Contains(supportedTlsMinVersions, spec.Config.Main.TlsMinVersion) {
+		errs = append(errs, field.NotSupported(configPath.Child("main", "tls_min_version"), spec.Config.Main.TlsMinVersion, supportedTlsMinVersions))
+	}
+
+	storage, ok := spec.Persistence.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage]
+	storagePath := specPath.Child("persistence", "dataVolumeClaim", "resources", "requests").Key(string(corev1.ResourceStorage))
+	if !ok {
+		errs = append(errs, field.Required(storagePath, "the size of the data volumes must be set"))
+	} else if storage.Sign() <= 0 {
+		errs = append(errs, field.Invalid(storagePath, storage.String(), "must be greater than zero"))
+	}
+
+	return errs
+}
+
+// validateSpecUpdate returns the problems with changing the spec from oldSpec to newSpec.
+func validateSpecUpdate(oldSpec, newSpec *LavinMQSpec) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for _, claim := range changedVolumeClaims(oldSpec, newSpec) {
+		oldStorage, hadStorage := claim.old.Resources.Requests[corev1.ResourceStorage]
+		newStorage, hasStorage := claim.new.Resources.Requests[corev1.ResourceStorage]
+		if hadStorage && hasStorage && newStorage.Cmp(oldStorage) < 0 {
+			storagePath := claim.path.Child("resources", "requests").Key(string(corev1.ResourceStorage))
+			errs = append(errs, field.Forbidden(storagePath,
+				fmt.Sprintf("volumes can't shrink, from %s to %s, only increasing the size is supported", oldStorage.String(), newStorage.String())))
+		}
+	}
+
+	return errs
+}
+
+// validateImmutable rejects changes to fields that can't be changed on the existing PersistentVolumeClaims.
+func validateImmutable(oldSpec, newSpec *LavinMQSpec) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for _, claim := range changedVolumeClaims(oldSpec, newSpec) {
+		claimPath, oldClaim, newClaim := claim.path, claim.old, claim.new
+		if !equality.Semantic.DeepEqual(oldClaim.VolumeMode, newClaim.VolumeMode) {
+			errs = append(errs, apivalidation.ValidateImmutableField(newClaim.VolumeMode, oldClaim.VolumeMode, claimPath.Child("volumeMode"))...)
+		}
+		if !equality.Semantic.DeepEqual(oldClaim.Selector, newClaim.Selector) {
+			errs = append(errs, apivalidation.ValidateImmutableField(newClaim.Selector, oldClaim.Selector, claimPath.Child("selector"))...)
+		}
+		if oldClaim.VolumeName != newClaim.VolumeName {
+			errs = append(errs, apivalidation.ValidateImmutableField(newClaim.VolumeName, oldClaim.VolumeName, claimPath.Child("volumeName"))...)
+		}
+		if !equality.Semantic.DeepEqual(oldClaim.DataSource, newClaim.DataSource) {
+			errs = append(errs, apivalidation.ValidateImmutableField(newClaim.DataSource, oldClaim.DataSource, claimPath.Child("dataSource"))...)
+		}
+		if !equality.Semantic.DeepEqual(oldClaim.DataSourceRef, newClaim.DataSourceRef) {
+			errs = append(errs, apivalidation.ValidateImmutableField(newClaim.DataSourceRef, oldClaim.DataSourceRef, claimPath.Child("dataSourceRef"))...)
+		}
+		// Only the data volumes are migrated to a new StorageClass.
+		if claim.additional && !equality.Semantic.DeepEqual(oldClaim.StorageClassName, newClaim.StorageClassName) {
+			errs = append(errs, apivalidation.ValidateImmutableField(newClaim.StorageClassName, oldClaim.StorageClassName, claimPath.Child("storageClassName"))...)
+		}
+	}
+
+	return errs
+}
+
+// volumeClaimChange is a volume claim present both before and after an update.
+type volumeClaimChange struct {
+	path       *field.Path
+	old, new   *corev1.PersistentVolumeClaimSpec
+	additional bool
+}
+
+// changedVolumeClaims pairs the data volume claim and the additional volume claims of oldSpec and newSpec by name.
+func changedVolumeClaims(oldSpec, newSpec *LavinMQSpec) []volumeClaimChange {
+	claims := []volumeClaimChange{{
+		path: field.NewPath("spec", "persistence", "dataVolumeClaim"),
+		old:  &oldSpec.Persistence.DataVolumeClaimSpec,
+		new:  &newSpec.Persistence.DataVolumeClaimSpec,
+	}}
+
+	volumesPath := field.NewPath("spec", "persistence", "volumes")
+	for i, volume := range newSpec.Persistence.Volumes {
+		index := slices.IndexFunc(oldSpec.Persistence.Volumes, func(v VolumeSpec) bool { return v.Name == volume.Name })
+		if index == -1 || volume.VolumeClaimSpec == nil || oldSpec.Persistence.Volumes[index].VolumeClaimSpec == nil {
+			continue
+		}
+		claims = append(claims, volumeClaimChange{
+			path:       volumesPath.Index(i).Child("volumeClaim"),
+			old:        oldSpec.Persistence.Volumes[index].VolumeClaimSpec,
+			new:        volume.VolumeClaimSpec,
+			additional: true,
+		})
+	}
+
+	return claims
+}
+
+// dangerousChange is an allowed change to the spec that may disrupt clients or risk data.
+type dangerousChange struct {
+	path   *field.Path
+	detail string
+}
+
+// dangerousChanges lists the risky changes from oldSpec to newSpec, they require the ForceAnnotation.
+func dangerousChanges(oldSpec, newSpec *LavinMQSpec) []dangerousChange {
+	changes := []dangerousChange{}
+	specPath := field.NewPath("spec")
+
+	if oldSpec.Replicas > 1 && len(oldSpec.Clustering.EtcdEndpoints) > 0 && len(newSpec.Clustering.EtcdEndpoints) == 0 {
+		changes = append(changes, dangerousChange{specPath.Child("clustering", "etcdEndpoints"),
+			"removing etcd from a multi node cluster leaves only one node with the data, the followers lose theirs"})
+	}
+
+	// Clusters are migrated one node at a time, resyncing from the leader, single nodes lose their data.
+	oldClass, newClass := oldSpec.Persistence.DataVolumeClaimSpec.StorageClassName, newSpec.Persistence.DataVolumeClaimSpec.StorageClassName
+	replicated := newSpec.Replicas > 1 && newSpec.Clustering.Enabled()
+	if !replicated && oldClass != nil && (newClass == nil || *oldClass != *newClass) {
+		changes = append(changes, dangerousChange{specPath.Child("persistence", "dataVolumeClaim", "storageClassName"),
+			"changing the storage class without replicas to resync the data from moves the node to an empty volume"})
+	}
+
+	if gate := UpgradeGate(oldSpec.Image, newSpec.Image); gate != "" {
+		changes = append(changes, dangerousChange{specPath.Child("image"), gate})
+	}
+
+	configPath := specPath.Child("config")
+	listeners := []struct {
+		path     *field.Path
+		old, new int32
+	}{
+		{configPath.Child("mgmt", "port"), oldSpec.Config.Mgmt.Port, newSpec.Config.Mgmt.Port},
+		{configPath.Child("mgmt", "tls_port"), oldSpec.Config.Mgmt.TlsPort, newSpec.Config.Mgmt.TlsPort},
+		{configPath.Child("amqp", "port"), oldSpec.Config.Amqp.Port, newSpec.Config.Amqp.Port},
+		{configPath.Child("amqp", "tls_port"), oldSpec.Config.Amqp.TlsPort, newSpec.Config.Amqp.TlsPort},
+		{configPath.Child("mqtt", "port"), oldSpec.Config.Mqtt.Port, newSpec.Config.Mqtt.Port},
+		{configPath.Child("mqtt", "tls_port"), oldSpec.Config.Mqtt.TlsPort, newSpec.Config.Mqtt.TlsPort},
+	}
+	for _, listener := range listeners {
+		if listener.old > 0 && listener.new <= 0 {
+			changes = append(changes, dangerousChange{listener.path,
+				fmt.Sprintf("disabling the listener on port %d disconnects the clients using it", listener.old)})
+		}
+	}
+
+	return changes
+}
+
+// validateVolumes makes sure the additional volumes of the LavinMQ named name don't clash with the volumes and
+// mounts of the operator, nor each other.
+func validateVolumes(name string, spec *LavinMQSpec) field.ErrorList {
+	errs := field.ErrorList{}
+	volumesPath := field.NewPath("spec", "persistence", "volumes")
+
+	// The config volume is named after the LavinMQ.
+	names := map[string]string{"data": "the data volume", "tls": "the TLS volume", name: "the config volume"}
+	mountPaths := map[string]string{"/var/lib/lavinmq": "the data volume", "/etc/lavinmq": "the config volume"}
+	for i, volume := range spec.Persistence.Volumes {
+		path := volumesPath.Index(i)
+
+		if other, ok := names[volume.Name]; ok {
+			errs = append(errs, field.Invalid(path.Child("name"), volume.Name, fmt.Sprintf("name is already used by %s", other)))
+		}
+		names[volume.Name] = path.String()
+
+		mountPath := strings.TrimSuffix(volume.MountPath, "/")
+		if other, ok := mountPaths[mountPath]; ok {
+			errs = append(errs, field.Invalid(path.Child("mountPath"), volume.MountPath, fmt.Sprintf("path is already mounted by %s", other)))
+		} else if strings.HasPrefix(mountPath, "/etc/lavinmq/") {
+			errs = append(errs, field.Invalid(path.Child("mountPath"), volume.MountPath, "path is inside the read only config volume"))
+		}
+		mountPaths[mountPath] = path.String()
+
+		if (volume.VolumeClaimSpec == nil) == (volume.EmptyDir == nil) {
+			errs = append(errs, field.Invalid(path, volume.Name, "exactly one of volumeClaim and emptyDir must be set"))
+			continue
+		}
+
+		if volume.VolumeClaimSpec != nil {
+			storage, ok := volume.VolumeClaimSpec.Resources.Requests[corev1.ResourceStorage]
+			storagePath := path.Child("volumeClaim", "resources", "requests").Key(string(corev1.ResourceStorage))
+			if !ok {
+				errs = append(errs, field.Required(storagePath, "the size of the volumes must be set"))
+			} else if storage.Sign() <= 0 {
+				errs = append(errs, field.Invalid(storagePath, storage.String(), "must be greater than zero"))
+			}
+		}
+	}
+
+	return errs
+}
+
+// validatePorts makes sure the enabled listeners don't share ports and that TLS listeners have a certificate.
+func validatePorts(spec *LavinMQSpec, configPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	ports := []struct {
+		path *field.Path
+		port int32
+		tls  bool
+	}{
+		{configPath.Child("mgmt", "port"), spec.Config.Mgmt.Port, false},
+		{configPath.Child("mgmt", "tls_port"), spec.Config.Mgmt.TlsPort, true},
+		{configPath.Child("amqp", "port"), spec.Config.Amqp.Port, false},
+		{configPath.Child("amqp", "tls_port"), spec.Config.Amqp.TlsPort, true},
+		{configPath.Child("mqtt", "port"), spec.Config.Mqtt.Port, false},
+		{configPath.Child("mqtt", "tls_port"), spec.Config.Mqtt.TlsPort, true},
+	}
+
+	used := map[int32]string{}
+	if len(spec.Clustering.EtcdEndpoints) > 0 {
+		used[ClusteringPort] = "the clustering port"
+	}
+
+	for _, p := range ports {
+		if p.port <= 0 {
+			continue
+		}
+
+		if p.tls && spec.TLS == nil {
+			errs = append(errs, field.Forbidden(p.path, "TLS listeners require spec.tls.secretName to be set"))
+		}
+
+		if other, ok := used[p.port]; ok {
+			errs = append(errs, field.Invalid(p.path, p.port, fmt.Sprintf("port is already used by %s", other)))
+			continue
+		}
+		used[p.port] = p.path.String()
+	}
+
+	return errs
+}
+
+// validateTcpKeepalive accepts "false" or the idle, interval and probe count as "idle:interval:count".
+func validateTcpKeepalive(value string) error {
+	if value == "false" {
+		return nil
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return fmt.Errorf("must be false or idle:interval:count, e.g. 60:10:3")
+	}
+
+	for _, part := range parts {
+		if n, err := strconv.ParseUint(part, 10, 32); err != nil || n == 0 {
+			return fmt.Errorf("must be false or idle:interval:count with positive integers, e.g. 60:10:3")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var lavinmqlog = logf.Log.WithName("lavinmq-resource")
+
+// Defaults filled in by the defaulting webhook.
+const (
+	DefaultImage    = "cloudamqp/lavinmq:2.4.1"
+	DefaultMgmtPort = 15672
+	DefaultAmqpPort = 5672
+	DefaultMqttPort = 1883
+
+	DefaultTerminationGracePeriodSeconds = 60
+)
+
+// InstanceLabel is set on the pods to the name of the LavinMQ they belong to.
+const InstanceLabel = "app.kubernetes.io/instance"
+
+// Annotation marking the StorageClass used for claims without a storageClassName.
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// DefaultResources are the container resources used when neither requests nor limits are given.
+var DefaultResources = corev1.ResourceRequirements{
+	Requests: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("250m"),
+		corev1.ResourceMemory: resource.MustParse("512Mi"),
+	},
+}
+
+// SetupWebhookWithManager will setup the manager to manage the webhooks
+func (r *LavinMQ) SetupWebhookWithManager(mgr ctrl.Manager) error {
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&LavinMQCustomDefaulter{Client: mgr.GetAPIReader()}).
+		WithValidator(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-cloudamqp-com-v1beta1-lavinmq,mutating=true,failurePolicy=fail,sideEffects=None,groups=cloudamqp.com,resources=lavinmqs,verbs=create;update,versions=v1beta1,name=mlavinmq.kb.io,admissionReviewVersions=v1
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+
+// LavinMQCustomDefaulter fills in the defaults of a LavinMQ, so that the stored spec describes what is deployed.
+// +kubebuilder:object:generate=false
+type LavinMQCustomDefaulter struct {
+	// Client used to look up the default StorageClass, when nil the storage class is left unset.
+	Client client.Reader
+}
+
+var _ webhook.CustomDefaulter = &LavinMQCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type
+func (d *LavinMQCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	lavin, ok := obj.(*LavinMQ)
+	if !ok {
+		return fmt.Errorf("expected a LavinMQ object but got %T", obj)
+	}
+	lavinmqlog.Info("defaulting", "name", lavin.Name)
+
+	spec := &lavin.Spec
+	if spec.Image == "" {
+		spec.Image = DefaultImage
+	}
+	if spec.Replicas == 0 {
+		spec.Replicas = 1
+	}
+	if len(spec.Resources.Requests) == 0 && len(spec.Resources.Limits) == 0 {
+		spec.Resources.Requests = DefaultResources.Requests.DeepCopy()
+	}
+	if spec.Config.Mgmt.Port == 0 {
+		spec.Config.Mgmt.Port = DefaultMgmtPort
+	}
+	if spec.Config.Amqp.Port == 0 {
+		spec.Config.Amqp.Port = DefaultAmqpPort
+	}
+	if spec.Config.Mqtt.Port == 0 {
+		spec.Config.Mqtt.Port = DefaultMqttPort
+	}
+	if spec.Affinity == nil {
+		spec.Affinity = defaultAffinity(lavin.Name)
+	}
+	if spec.TerminationGracePeriodSeconds == nil {
+		spec.TerminationGracePeriodSeconds = ptr.To(int64(DefaultTerminationGracePeriodSeconds))
+	}
+
+	// Only new instances get the default StorageClass. Filling it in on an update, after the default of the cluster
+	// changed, would move the volumes to the new default.
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.Operation != admissionv1.Create {
+		return nil
+	}
+
+	claims := []*corev1.PersistentVolumeClaimSpec{&spec.Persistence.DataVolumeClaimSpec}
+	for _, volume := range spec.Persistence.Volumes {
+		if volume.VolumeClaimSpec != nil {
+			claims = append(claims, volume.VolumeClaimSpec)
+		}
+	}
+	for _, claim := range claims {
+		if claim.StorageClassName == nil {
+			storageClass, err := d.defaultStorageClass(ctx)
+			if err != nil {
+				return err
+			}
+			claim.StorageClassName = storageClass
+		}
+	}
+
+	return nil
+}
+
+// defaultStorageClass returns the name of the cluster's default StorageClass, nil if there is none.
+func (d *LavinMQCustomDefaulter) defaultStorageClass(ctx context.Context) (*string, error) {
+	if d.Client == nil {
+		return nil, nil
+	}
+
+	storageClasses := &storagev1.StorageClassList{}
+	if err := d.Client.List(ctx, storageClasses); err != nil {
+		return nil, fmt.Errorf("failed to list storage classes: %w", err)
+	}
+
+	for _, storageClass := range storageClasses.Items {
+		if storageClass.Annotations[defaultStorageClassAnnotation] == "true" {
+			return &storageClass.Name, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// defaultAffinity prefers spreading the pods of an instance over different nodes.
+func defaultAffinity(name string) *corev1.Affinity {
+	return &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{InstanceLabel: name},
+						},
+						TopologyKey: corev1.LabelHostname,
+					},
+				},
+			},
+		},
+	}
+}
+
+// TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
+// NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
+// Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
+// +kubebuilder:webhook:path=/validate-cloudamqp-com-v1beta1-lavinmq,mutating=false,failurePolicy=fail,sideEffects=None,groups=cloudamqp.com,resources=lavinmqs,verbs=create;update,versions=v1beta1,name=vlavinmq.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomValidator = &LavinMQ{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *LavinMQ) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	lavin := obj.(*LavinMQ)
+	lavinmqlog.Info("validating create", "name", lavin.Name)
+	if lavin.Spec.Replicas > 1 && len(lavin.Spec.Clustering.EtcdEndpoints) == 0 {
+		return nil, fmt.Errorf("a provided etcd cluster is required for replication")
+	}
+	errs := validateSpec(&lavin.Spec)
+	errs = append(errs, validateVolumes(lavin.Name, &lavin.Spec)...)
+	return nil, invalid(lavin, errs)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *LavinMQ) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	newLavinMQ := newObj.(*LavinMQ)
+	oldLavinMQ := oldObj.(*LavinMQ)
+	lavinmqlog.Info("validating update", "name", newLavinMQ.Name)
+	if newLavinMQ.Spec.Replicas > 1 && len(newLavinMQ.Spec.Clustering.EtcdEndpoints) == 0 {
+		return nil, fmt.Errorf("a provided etcd cluster is required for replication")
+	}
+	if oldLavinMQ.Spec.Replicas == 1 && len(oldLavinMQ.Spec.Clustering.EtcdEndpoints) == 0 {
+		if newLavinMQ.Spec.Replicas > 1 {
+			return nil, fmt.Errorf("in order to safely transition without message loss from single to multi node, first update to run the single node with etcd cluster, then update to multi node")
+		}
+	}
+	errs := validateSpec(&newLavinMQ.Spec)
+	errs = append(errs, validateVolumes(newLavinMQ.Name, &newLavinMQ.Spec)...)
+	errs = append(errs, validateSpecUpdate(&oldLavinMQ.Spec, &newLavinMQ.Spec)...)
+	errs = append(errs, validateImmutable(&oldLavinMQ.Spec, &newLavinMQ.Spec)...)
+
+	warnings := admission.Warnings{}
+	forced := newLavinMQ.Annotations[ForceAnnotation] == "true"
+	for _, change := range dangerousChanges(&oldLavinMQ.Spec, &newLavinMQ.Spec) {
+		warnings = append(warnings, fmt.Sprintf("%s: %s", change.path, change.detail))
+		if !forced {
+			errs = append(errs, field.Forbidden(change.path,
+				fmt.Sprintf("%s, set the annotation %s: \"true\" to allow it", change.detail, ForceAnnotation)))
+		}
+	}
+	if len(warnings) == 0 {
+		warnings = nil
+	}
+
+	return warnings, invalid(newLavinMQ, errs)
+}
+
+// invalid turns the field errors into an Invalid API error, nil when there are none.
+func invalid(lavin *LavinMQ, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("LavinMQ").GroupKind(), lavin.Name, errs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *LavinMQ) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
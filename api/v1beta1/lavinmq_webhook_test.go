@@ -0,0 +1,549 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestCreateDefault(t *testing.T) {
+	t.Parallel()
+	lavinMQ := &LavinMQ{Spec: LavinMQSpec{Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()}}}
+	_, err := lavinMQ.ValidateCreate(context.TODO(), lavinMQ)
+	assert.NoErrorf(t, err, "Failed to validate update")
+}
+
+func TestCreateClusterWithEtcd(t *testing.T) {
+	t.Parallel()
+	lavinMQ := &LavinMQ{Spec: LavinMQSpec{
+		Replicas:    3,
+		Clustering:  ClusteringSpec{EtcdEndpoints: []string{"http://etcd-cluster:2379"}},
+		Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()},
+	},
+	}
+	_, err := lavinMQ.ValidateCreate(context.TODO(), lavinMQ)
+	assert.NoErrorf(t, err, "Failed to validate create")
+}
+
+func TestCreateClusterWithoutEtcd(t *testing.T) {
+	t.Parallel()
+	lavinMQ := &LavinMQ{Spec: LavinMQSpec{
+		Replicas: 3,
+	},
+	}
+	_, err := lavinMQ.ValidateCreate(context.TODO(), lavinMQ)
+	assert.Errorf(t, err, "Expected error when creating cluster without etcd")
+	assert.Equal(t, err.Error(), "a provided etcd cluster is required for replication")
+}
+
+func TestUpdateDefault(t *testing.T) {
+	t.Parallel()
+	oldLavinMQ := &LavinMQ{Spec: LavinMQSpec{Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()}}}
+	newLavinMQ := &LavinMQ{Spec: LavinMQSpec{Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()}}}
+	_, err := newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+	assert.NoErrorf(t, err, "Failed to validate update")
+}
+
+func TestUpdateStandaloneToCluster(t *testing.T) {
+	t.Parallel()
+	oldLavinMQ := &LavinMQ{Spec: LavinMQSpec{
+		Replicas: 1,
+	}}
+	newLavinMQ := &LavinMQ{Spec: LavinMQSpec{
+		Replicas:   3,
+		Clustering: ClusteringSpec{EtcdEndpoints: []string{"http://etcd-cluster:2379"}},
+	}}
+	_, err := newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+	assert.Errorf(t, err, "Expected error when updating from standalone to cluster without etcd")
+	assert.Equal(t, err.Error(), "in order to safely transition without message loss from single to multi node, first update to run the single node with etcd cluster, then update to multi node")
+}
+
+func TestUpdateStandaloneToClusterNoEtcd(t *testing.T) {
+	t.Parallel()
+	oldLavinMQ := &LavinMQ{Spec: LavinMQSpec{
+		Replicas: 1,
+	}}
+	newLavinMQ := &LavinMQ{Spec: LavinMQSpec{
+		Replicas: 3,
+	}}
+	_, err := newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+	assert.Errorf(t, err, "Expected error when updating from standalone to cluster without etcd")
+	assert.Equal(t, err.Error(), "a provided etcd cluster is required for replication")
+}
+
+func TestUpdateStandaloneWithEtcd(t *testing.T) {
+	t.Parallel()
+	oldLavinMQ := &LavinMQ{Spec: LavinMQSpec{
+		Replicas:    1,
+		Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()},
+	}}
+	newLavinMQ := &LavinMQ{Spec: LavinMQSpec{
+		Replicas:    1,
+		Clustering:  ClusteringSpec{EtcdEndpoints: []string{"http://etcd-cluster:2379"}},
+		Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()},
+	}}
+	_, err := newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+	assert.NoErrorf(t, err, "Failed to validate update")
+}
+
+func TestUpdateStandaloneWithEtcdToCluster(t *testing.T) {
+	t.Parallel()
+	oldLavinMQ := &LavinMQ{Spec: LavinMQSpec{
+		Replicas:    1,
+		Clustering:  ClusteringSpec{EtcdEndpoints: []string{"http://etcd-cluster:2379"}},
+		Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()},
+	}}
+	newLavinMQ := &LavinMQ{Spec: LavinMQSpec{
+		Replicas:    3,
+		Clustering:  ClusteringSpec{EtcdEndpoints: []string{"http://etcd-cluster:2379"}},
+		Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()},
+	}}
+	_, err := newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+	assert.NoErrorf(t, err, "Failed to validate update")
+}
+
+func TestDeleteDefault(t *testing.T) {
+	t.Parallel()
+	lavinMQ := &LavinMQ{}
+	_, err := lavinMQ.ValidateDelete(context.TODO(), lavinMQ)
+	assert.NoErrorf(t, err, "Failed to validate update")
+}
+
+func TestCreateInvalidSpec(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		spec  LavinMQSpec
+		field string
+		kind  field.ErrorType
+	}{
+		"port collision": {
+			spec:  LavinMQSpec{Config: LavinMQConfig{Amqp: AmqpConfig{Port: 1883}, Mqtt: MqttConfig{Port: 1883}}},
+			field: "spec.config.mqtt.port",
+			kind:  field.ErrorTypeInvalid,
+		},
+		"tls port collision": {
+			spec: LavinMQSpec{
+				TLS:    &TLSSpec{SecretName: "tls"},
+				Config: LavinMQConfig{Amqp: AmqpConfig{Port: 5672, TlsPort: 5672}},
+			},
+			field: "spec.config.amqp.tls_port",
+			kind:  field.ErrorTypeInvalid,
+		},
+		"clustering port collision": {
+			spec: LavinMQSpec{
+				Clustering: ClusteringSpec{EtcdEndpoints: []string{"http://etcd-cluster:2379"}},
+				Config:     LavinMQConfig{Mgmt: MgmtConfig{Port: 5679}},
+			},
+			field: "spec.config.mgmt.port",
+			kind:  field.ErrorTypeInvalid,
+		},
+		"tls port without secret": {
+			spec:  LavinMQSpec{Config: LavinMQConfig{Mqtt: MqttConfig{TlsPort: 8883}}},
+			field: "spec.config.mqtt.tls_port",
+			kind:  field.ErrorTypeForbidden,
+		},
+		"log level": {
+			spec:  LavinMQSpec{Config: LavinMQConfig{Main: MainConfig{LogLevel: "verbose"}}},
+			field: "spec.config.main.log_level",
+			kind:  field.ErrorTypeNotSupported,
+		},
+		"tcp keepalive": {
+			spec:  LavinMQSpec{Config: LavinMQConfig{Main: MainConfig{TcpKeepalive: "60:10"}}},
+			field: "spec.config.main.tcp_keepalive",
+			kind:  field.ErrorTypeInvalid,
+		},
+		"tls min version": {
+			spec:  LavinMQSpec{Config: LavinMQConfig{Main: MainConfig{TlsMinVersion: "TLSv1.2"}}},
+			field: "spec.config.main.tls_min_version",
+			kind:  field.ErrorTypeNotSupported,
+		},
+		"reserved volume name": {
+			spec:  LavinMQSpec{Persistence: PersistenceSpec{Volumes: []VolumeSpec{{Name: "tls", MountPath: "/tmp", EmptyDir: &corev1.EmptyDirVolumeSource{}}}}},
+			field: "spec.persistence.volumes[0].name",
+			kind:  field.ErrorTypeInvalid,
+		},
+		"volume mount path collision": {
+			spec: LavinMQSpec{Persistence: PersistenceSpec{Volumes: []VolumeSpec{
+				{Name: "scratch", MountPath: "/tmp", EmptyDir: &corev1.EmptyDirVolumeSource{}},
+				{Name: "cache", MountPath: "/tmp/", EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			}}},
+			field: "spec.persistence.volumes[1].mountPath",
+			kind:  field.ErrorTypeInvalid,
+		},
+		"volume in config directory": {
+			spec:  LavinMQSpec{Persistence: PersistenceSpec{Volumes: []VolumeSpec{{Name: "scratch", MountPath: "/etc/lavinmq/extra", EmptyDir: &corev1.EmptyDirVolumeSource{}}}}},
+			field: "spec.persistence.volumes[0].mountPath",
+			kind:  field.ErrorTypeInvalid,
+		},
+		"volume without source": {
+			spec:  LavinMQSpec{Persistence: PersistenceSpec{Volumes: []VolumeSpec{{Name: "scratch", MountPath: "/tmp"}}}},
+			field: "spec.persistence.volumes[0]",
+			kind:  field.ErrorTypeInvalid,
+		},
+		"pod label": {
+			spec:  LavinMQSpec{PodLabels: map[string]string{"team": "-messaging"}},
+			field: "spec.podLabels",
+			kind:  field.ErrorTypeInvalid,
+		},
+		"pod annotation": {
+			spec:  LavinMQSpec{PodAnnotations: map[string]string{"not a key": "true"}},
+			field: "spec.podAnnotations",
+			kind:  field.ErrorTypeInvalid,
+		},
+		"volume claim without storage": {
+			spec: LavinMQSpec{Persistence: PersistenceSpec{Volumes: []VolumeSpec{
+				{Name: "segments", MountPath: "/var/lib/lavinmq/segments", VolumeClaimSpec: &corev1.PersistentVolumeClaimSpec{}},
+			}}},
+			field: "spec.persistence.volumes[0].volumeClaim.resources.requests[storage]",
+			kind:  field.ErrorTypeRequired,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			lavinMQ := &LavinMQ{Spec: c.spec}
+			lavinMQ.Spec.Persistence.DataVolumeClaimSpec = dataVolumeClaim()
+			_, err := lavinMQ.ValidateCreate(context.TODO(), lavinMQ)
+			assertFieldError(t, err, c.field, c.kind)
+		})
+	}
+}
+
+func TestCreateValidConfig(t *testing.T) {
+	t.Parallel()
+	lavinMQ := &LavinMQ{Spec: LavinMQSpec{
+		TLS:         &TLSSpec{SecretName: "tls"},
+		Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()},
+		Config: LavinMQConfig{
+			Main: MainConfig{LogLevel: "debug", TcpKeepalive: "60:10:3", TlsMinVersion: "1.2"},
+			Mgmt: MgmtConfig{Port: -1, TlsPort: 15671},
+			Amqp: AmqpConfig{Port: 5672, TlsPort: 5671},
+			Mqtt: MqttConfig{Port: -1, TlsPort: 8883},
+		},
+	}}
+	_, err := lavinMQ.ValidateCreate(context.TODO(), lavinMQ)
+	assert.NoErrorf(t, err, "Failed to validate create")
+}
+
+func TestCreateWithoutStorage(t *testing.T) {
+	t.Parallel()
+	lavinMQ := &LavinMQ{}
+	_, err := lavinMQ.ValidateCreate(context.TODO(), lavinMQ)
+	assertFieldError(t, err, "spec.persistence.dataVolumeClaim.resources.requests[storage]", field.ErrorTypeRequired)
+}
+
+func TestUpdateStorageShrink(t *testing.T) {
+	t.Parallel()
+	oldLavinMQ := &LavinMQ{Spec: LavinMQSpec{Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()}}}
+	newLavinMQ := oldLavinMQ.DeepCopy()
+	newLavinMQ.Spec.Persistence.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("5Gi")
+	_, err := newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+	assertFieldError(t, err, "spec.persistence.dataVolumeClaim.resources.requests[storage]", field.ErrorTypeForbidden)
+
+	newLavinMQ.Spec.Persistence.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("20Gi")
+	_, err = newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+	assert.NoErrorf(t, err, "Failed to validate update")
+}
+
+func TestUpdateImmutableVolumeMode(t *testing.T) {
+	t.Parallel()
+	oldLavinMQ := &LavinMQ{Spec: LavinMQSpec{Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()}}}
+	newLavinMQ := oldLavinMQ.DeepCopy()
+	volumeMode := corev1.PersistentVolumeBlock
+	newLavinMQ.Spec.Persistence.DataVolumeClaimSpec.VolumeMode = &volumeMode
+	newLavinMQ.Annotations = map[string]string{ForceAnnotation: "true"}
+	_, err := newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+	assertFieldError(t, err, "spec.persistence.dataVolumeClaim.volumeMode", field.ErrorTypeInvalid)
+}
+
+func TestUpdateAdditionalVolumeClaim(t *testing.T) {
+	t.Parallel()
+	claim := dataVolumeClaim()
+	claim.StorageClassName = ptr.To("nvme")
+	oldLavinMQ := &LavinMQ{Spec: LavinMQSpec{Persistence: PersistenceSpec{
+		DataVolumeClaimSpec: dataVolumeClaim(),
+		Volumes:             []VolumeSpec{{Name: "segments", MountPath: "/var/lib/lavinmq/segments", VolumeClaimSpec: &claim}},
+	}}}
+
+	newLavinMQ := oldLavinMQ.DeepCopy()
+	newLavinMQ.Spec.Persistence.Volumes[0].VolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("5Gi")
+	_, err := newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+	assertFieldError(t, err, "spec.persistence.volumes[0].volumeClaim.resources.requests[storage]", field.ErrorTypeForbidden)
+
+	newLavinMQ = oldLavinMQ.DeepCopy()
+	newLavinMQ.Spec.Persistence.Volumes[0].VolumeClaimSpec.StorageClassName = ptr.To("standard")
+	_, err = newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+	assertFieldError(t, err, "spec.persistence.volumes[0].volumeClaim.storageClassName", field.ErrorTypeInvalid)
+
+	newLavinMQ = oldLavinMQ.DeepCopy()
+	newLavinMQ.Spec.Persistence.Volumes[0].VolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("20Gi")
+	newLavinMQ.Spec.Persistence.Volumes = append(newLavinMQ.Spec.Persistence.Volumes,
+		VolumeSpec{Name: "scratch", MountPath: "/tmp", EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}})
+	_, err = newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+	assert.NoErrorf(t, err, "Failed to validate update")
+}
+
+func TestUpdateDangerousChanges(t *testing.T) {
+	t.Parallel()
+	standard, fast := "standard", "fast"
+	cases := map[string]struct {
+		old, new LavinMQSpec
+		field    string
+	}{
+		"removing etcd from cluster": {
+			old:   LavinMQSpec{Replicas: 3, Clustering: ClusteringSpec{EtcdEndpoints: []string{"http://etcd-cluster:2379"}}},
+			new:   LavinMQSpec{Replicas: 1},
+			field: "spec.clustering.etcdEndpoints",
+		},
+		"changing storage class": {
+			old:   LavinMQSpec{Persistence: PersistenceSpec{DataVolumeClaimSpec: corev1.PersistentVolumeClaimSpec{StorageClassName: &standard}}},
+			new:   LavinMQSpec{Persistence: PersistenceSpec{DataVolumeClaimSpec: corev1.PersistentVolumeClaimSpec{StorageClassName: &fast}}},
+			field: "spec.persistence.dataVolumeClaim.storageClassName",
+		},
+		"disabling a listener": {
+			old:   LavinMQSpec{Config: LavinMQConfig{Amqp: AmqpConfig{Port: 5672}}},
+			new:   LavinMQSpec{Config: LavinMQConfig{Amqp: AmqpConfig{Port: -1}}},
+			field: "spec.config.amqp.port",
+		},
+		"downgrading": {
+			old:   LavinMQSpec{Image: "cloudamqp/lavinmq:2.4.1"},
+			new:   LavinMQSpec{Image: "cloudamqp/lavinmq:2.3.0"},
+			field: "spec.image",
+		},
+		"skipping a major version": {
+			old:   LavinMQSpec{Image: "cloudamqp/lavinmq:1.3.1"},
+			new:   LavinMQSpec{Image: "registry.example.com:5000/cloudamqp/lavinmq:v3.0.0"},
+			field: "spec.image",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			oldLavinMQ := &LavinMQ{Spec: c.old}
+			newLavinMQ := &LavinMQ{Spec: c.new}
+			oldLavinMQ.Spec.Persistence.DataVolumeClaimSpec.Resources = dataVolumeClaim().Resources
+			newLavinMQ.Spec.Persistence.DataVolumeClaimSpec.Resources = dataVolumeClaim().Resources
+
+			warnings, err := newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+			assertFieldError(t, err, c.field, field.ErrorTypeForbidden)
+			assert.Len(t, warnings, 1)
+
+			newLavinMQ.Annotations = map[string]string{ForceAnnotation: "true"}
+			warnings, err = newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+			assert.NoErrorf(t, err, "Forced change should be allowed")
+			assert.Len(t, warnings, 1)
+			assert.Contains(t, warnings[0], c.field)
+		})
+	}
+}
+
+func TestUpdateImageIsNotDangerous(t *testing.T) {
+	t.Parallel()
+	images := map[string]string{
+		"patch upgrade":       "cloudamqp/lavinmq:2.4.2",
+		"major upgrade":       "cloudamqp/lavinmq:3.0.0",
+		"tag without version": "cloudamqp/lavinmq:latest",
+		"digest":              "cloudamqp/lavinmq@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}
+
+	for name, image := range images {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			oldLavinMQ := &LavinMQ{Spec: LavinMQSpec{Image: "cloudamqp/lavinmq:2.4.1", Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()}}}
+			newLavinMQ := oldLavinMQ.DeepCopy()
+			newLavinMQ.Spec.Image = image
+			warnings, err := newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+			assert.NoErrorf(t, err, "Failed to validate update")
+			assert.Empty(t, warnings)
+		})
+	}
+}
+
+func TestUpdateSettingStorageClassIsNotDangerous(t *testing.T) {
+	t.Parallel()
+	standard := "standard"
+	oldLavinMQ := &LavinMQ{Spec: LavinMQSpec{Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()}}}
+	newLavinMQ := oldLavinMQ.DeepCopy()
+	newLavinMQ.Spec.Persistence.DataVolumeClaimSpec.StorageClassName = &standard
+	warnings, err := newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+	assert.NoErrorf(t, err, "Failed to validate update")
+	assert.Empty(t, warnings)
+}
+
+func TestUpdateStorageClassOfClusterIsNotDangerous(t *testing.T) {
+	t.Parallel()
+	standard, fast := "standard", "fast"
+	oldLavinMQ := &LavinMQ{Spec: LavinMQSpec{
+		Replicas:    3,
+		Clustering:  ClusteringSpec{EtcdEndpoints: []string{"http://etcd-cluster:2379"}},
+		Persistence: PersistenceSpec{DataVolumeClaimSpec: dataVolumeClaim()},
+	}}
+	oldLavinMQ.Spec.Persistence.DataVolumeClaimSpec.StorageClassName = &standard
+	newLavinMQ := oldLavinMQ.DeepCopy()
+	newLavinMQ.Spec.Persistence.DataVolumeClaimSpec.StorageClassName = &fast
+
+	warnings, err := newLavinMQ.ValidateUpdate(context.TODO(), oldLavinMQ, newLavinMQ)
+	assert.NoErrorf(t, err, "Failed to validate update")
+	assert.Empty(t, warnings)
+}
+
+func assertFieldError(t *testing.T, err error, path string, kind field.ErrorType) {
+	t.Helper()
+	statusErr := &apierrors.StatusError{}
+	if !assert.ErrorAs(t, err, &statusErr) {
+		return
+	}
+	assert.True(t, apierrors.IsInvalid(err))
+	assert.Len(t, statusErr.ErrStatus.Details.Causes, 1)
+	for _, cause := range statusErr.ErrStatus.Details.Causes {
+		assert.Equal(t, path, cause.Field)
+		assert.Equal(t, metav1.CauseType(kind), cause.Type)
+	}
+}
+
+func dataVolumeClaim() corev1.PersistentVolumeClaimSpec {
+	return corev1.PersistentVolumeClaimSpec{
+		Resources: corev1.VolumeResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+		},
+	}
+}
+
+func TestDefaultFillsSpec(t *testing.T) {
+	t.Parallel()
+	lavinMQ := &LavinMQ{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+	err := (&LavinMQCustomDefaulter{}).Default(context.TODO(), lavinMQ)
+	assert.NoErrorf(t, err, "Failed to default")
+
+	assert.Equal(t, DefaultImage, lavinMQ.Spec.Image)
+	assert.Equal(t, int32(1), lavinMQ.Spec.Replicas)
+	assert.Equal(t, int32(15672), lavinMQ.Spec.Config.Mgmt.Port)
+	assert.Equal(t, int32(5672), lavinMQ.Spec.Config.Amqp.Port)
+	assert.Equal(t, int32(1883), lavinMQ.Spec.Config.Mqtt.Port)
+	assert.Equal(t, DefaultResources, lavinMQ.Spec.Resources)
+	assert.Equal(t, int64(60), *lavinMQ.Spec.TerminationGracePeriodSeconds)
+	assert.Nil(t, lavinMQ.Spec.Persistence.DataVolumeClaimSpec.StorageClassName)
+
+	terms := lavinMQ.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	assert.Len(t, terms, 1)
+	assert.Equal(t, map[string]string{InstanceLabel: "default"}, terms[0].PodAffinityTerm.LabelSelector.MatchLabels)
+	assert.Equal(t, corev1.LabelHostname, terms[0].PodAffinityTerm.TopologyKey)
+}
+
+func TestDefaultKeepsExplicitValues(t *testing.T) {
+	t.Parallel()
+	storageClass := "fast"
+	resources := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}
+	affinity := &corev1.Affinity{}
+	lavinMQ := &LavinMQ{Spec: LavinMQSpec{
+		Image:     "cloudamqp/lavinmq:2.3.0",
+		Replicas:  3,
+		Resources: resources,
+		Affinity:  affinity,
+
+		TerminationGracePeriodSeconds: ptr.To(int64(300)),
+		Persistence: PersistenceSpec{DataVolumeClaimSpec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &storageClass,
+		}},
+		Config: LavinMQConfig{
+			Amqp: AmqpConfig{Port: -1},
+			Mqtt: MqttConfig{Port: 11883},
+		},
+	}}
+	err := (&LavinMQCustomDefaulter{}).Default(context.TODO(), lavinMQ)
+	assert.NoErrorf(t, err, "Failed to default")
+
+	assert.Equal(t, "cloudamqp/lavinmq:2.3.0", lavinMQ.Spec.Image)
+	assert.Equal(t, int32(3), lavinMQ.Spec.Replicas)
+	assert.Equal(t, resources, lavinMQ.Spec.Resources)
+	assert.Same(t, affinity, lavinMQ.Spec.Affinity)
+	assert.Equal(t, int64(300), *lavinMQ.Spec.TerminationGracePeriodSeconds)
+	assert.Equal(t, "fast", *lavinMQ.Spec.Persistence.DataVolumeClaimSpec.StorageClassName)
+	assert.Equal(t, int32(-1), lavinMQ.Spec.Config.Amqp.Port)
+	assert.Equal(t, int32(11883), lavinMQ.Spec.Config.Mqtt.Port)
+}
+
+func TestDefaultStorageClass(t *testing.T) {
+	t.Parallel()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, storagev1.AddToScheme(scheme))
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "slow"}, Provisioner: "example.com/slow"},
+		&storagev1.StorageClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "standard",
+				Annotations: map[string]string{"storageclass.kubernetes.io/is-default-class": "true"},
+			},
+			Provisioner: "example.com/standard",
+		},
+	).Build()
+
+	lavinMQ := &LavinMQ{}
+	err := (&LavinMQCustomDefaulter{Client: client}).Default(context.TODO(), lavinMQ)
+	assert.NoErrorf(t, err, "Failed to default")
+	assert.Equal(t, "standard", *lavinMQ.Spec.Persistence.DataVolumeClaimSpec.StorageClassName)
+}
+
+func TestDefaultStorageClassOnlyOnCreate(t *testing.T) {
+	t.Parallel()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, storagev1.AddToScheme(scheme))
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&storagev1.StorageClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "standard",
+				Annotations: map[string]string{"storageclass.kubernetes.io/is-default-class": "true"},
+			},
+			Provisioner: "example.com/standard",
+		},
+	).Build()
+	defaulter := &LavinMQCustomDefaulter{Client: client}
+	request := func(operation admissionv1.Operation) context.Context {
+		return admission.NewContextWithRequest(context.TODO(), admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{Operation: operation},
+		})
+	}
+
+	lavinMQ := &LavinMQ{}
+	err := defaulter.Default(request(admissionv1.Update), lavinMQ)
+	assert.NoErrorf(t, err, "Failed to default")
+	assert.Nil(t, lavinMQ.Spec.Persistence.DataVolumeClaimSpec.StorageClassName,
+		"An update must not move the volumes to the current default StorageClass")
+	assert.Equal(t, DefaultImage, lavinMQ.Spec.Image)
+
+	err = defaulter.Default(request(admissionv1.Create), lavinMQ)
+	assert.NoErrorf(t, err, "Failed to default")
+	assert.Equal(t, "standard", *lavinMQ.Spec.Persistence.DataVolumeClaimSpec.StorageClassName)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Phases of a LavinMQSnapshot
+const (
+	SnapshotPhasePending    = "Pending"
+	SnapshotPhaseInProgress = "InProgress"
+	SnapshotPhaseReady      = "Ready"
+	SnapshotPhaseFailed     = "Failed"
+)
+
+// LavinMQSnapshotSpec defines which data volumes to snapshot
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable, create a new snapshot instead"
+type LavinMQSnapshotSpec struct {
+	// LavinMQ is the name of the instance, in the same namespace, whose data volumes are snapshotted
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	LavinMQ string `json:"lavinmq"`
+
+	// VolumeSnapshotClassName of the VolumeSnapshots, the cluster default is used if unset
+	// +optional
+	VolumeSnapshotClassName *string `json:"volumeSnapshotClassName,omitempty"`
+
+	// FollowersOnly skips the volume of the leader, keeping the snapshot I/O off the node serving the clients.
+	// Requires clustering.
+	// +optional
+	FollowersOnly bool `json:"followersOnly,omitempty"`
+}
+
+// LavinMQSnapshotStatus defines the observed state of LavinMQSnapshot
+type LavinMQSnapshotStatus struct {
+	// Phase is Pending, InProgress, Ready or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Message explains why the snapshot failed
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Volumes are the VolumeSnapshots taken, one per data volume
+	// +optional
+	Volumes []VolumeSnapshotStatus `json:"volumes,omitempty"`
+
+	// CompletionTime is when all VolumeSnapshots became ready to use
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// VolumeSnapshotStatus describes the VolumeSnapshot of one data volume
+type VolumeSnapshotStatus struct {
+	// PersistentVolumeClaim that was snapshotted, e.g. data-lavinmq-1
+	PersistentVolumeClaim string `json:"persistentVolumeClaim"`
+	// VolumeSnapshot is the name of the VolumeSnapshot
+	VolumeSnapshot string `json:"volumeSnapshot"`
+	// ReadyToUse is true once the snapshot can be restored from
+	ReadyToUse bool `json:"readyToUse"`
+	// Error reported by the snapshot controller
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="LavinMQ",type=string,JSONPath=`.spec.lavinmq`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// LavinMQSnapshot is a point-in-time snapshot of the data volumes of a LavinMQ
+type LavinMQSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   LavinMQSnapshotSpec   `json:"spec,omitempty"`
+	Status LavinMQSnapshotStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// LavinMQSnapshotList contains a list of LavinMQSnapshot
+type LavinMQSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LavinMQSnapshot `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LavinMQSnapshot{}, &LavinMQSnapshotList{})
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// ImageVersion parses the LavinMQ version from the tag of image, e.g. 2.4.1 from cloudamqp/lavinmq:2.4.1. Images
+// referenced by digest, or tagged with something else than a version like latest, have no version.
+func ImageVersion(image string) (*version.Version, error) {
+	if strings.Contains(image, "@") {
+		return nil, fmt.Errorf("image %s is referenced by digest", image)
+	}
+
+	name := image[strings.LastIndex(image, "/")+1:]
+	index := strings.LastIndex(name, ":")
+	if index == -1 {
+		return nil, fmt.Errorf("image %s has no tag", image)
+	}
+
+	return version.Parse(name[index+1:])
+}
+
+// UpgradeGate returns why changing the image from oldImage to newImage is risky, empty if it isn't. Downgrades
+// may not read the data written by the newer version and skipping a major version skips its data migrations.
+// Changes between images without a version can't be judged and are allowed.
+func UpgradeGate(oldImage, newImage string) string {
+	oldVersion, err := ImageVersion(oldImage)
+	if err != nil {
+		return ""
+	}
+	newVersion, err := ImageVersion(newImage)
+	if err != nil {
+		return ""
+	}
+
+	switch {
+	case newVersion.LessThan(oldVersion):
+		return fmt.Sprintf("downgrading from %s to %s may leave data the older version can't read", oldVersion, newVersion)
+	case newVersion.Major() > oldVersion.Major()+1:
+		return fmt.Sprintf("upgrading from %s to %s skips major version %d and its data migrations",
+			oldVersion, newVersion, oldVersion.Major()+1)
+	}
+
+	return ""
+}
@@ -17,8 +17,10 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"os"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
@@ -29,6 +31,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -36,7 +39,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	cloudamqpcomv1alpha1 "github.com/cloudamqp/lavinmq-operator/api/v1alpha1"
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
 	"github.com/cloudamqp/lavinmq-operator/internal/controller"
+	"github.com/cloudamqp/lavinmq-operator/internal/lavinmq"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -49,15 +54,26 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(cloudamqpcomv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(cloudamqpcomv1beta1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		if err := render(os.Args[2:], os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var watchNamespaceList string
+	var watchNamespaceSelector string
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -69,6 +85,12 @@ func main() {
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&watchNamespaceList, "watch-namespaces", os.Getenv("WATCH_NAMESPACES"),
+		"Comma separated namespaces to watch, defaults to the WATCH_NAMESPACES env var. "+
+			"All namespaces are watched if neither this nor --watch-namespace-selector is set.")
+	flag.StringVar(&watchNamespaceSelector, "watch-namespace-selector", os.Getenv("WATCH_NAMESPACE_SELECTOR"),
+		"Label selector of namespaces to watch, e.g. tenant-group=a, defaults to the WATCH_NAMESPACE_SELECTOR env var. "+
+			"Matching namespaces are looked up at start up.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -120,8 +142,25 @@ func main() {
 		metricsServerOptions.FilterProvider = filters.WithAuthenticationAndAuthorization
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	restConfig := ctrl.GetConfigOrDie()
+	var namespaces []string
+	if watchNamespaceList != "" || watchNamespaceSelector != "" {
+		namespaceClient, err := client.New(restConfig, client.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to create client")
+			os.Exit(1)
+		}
+		namespaces, err = watchNamespaces(context.Background(), namespaceClient, watchNamespaceList, watchNamespaceSelector)
+		if err != nil {
+			setupLog.Error(err, "unable to resolve the namespaces to watch")
+			os.Exit(1)
+		}
+		setupLog.Info("watching namespaces", "namespaces", namespaces)
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                 scheme,
+		Cache:                  cacheOptions(namespaces),
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
@@ -148,14 +187,23 @@ func main() {
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
 		Recorder: mgr.GetEventRecorderFor("lavinmq-controller"),
+		Reloader: &lavinmq.ExecConfigReloader{Config: mgr.GetConfig()},
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "LavinMQ")
 		os.Exit(1)
 	}
+	if err = (&controller.LavinMQSnapshotReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("lavinmqsnapshot-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "LavinMQSnapshot")
+		os.Exit(1)
+	}
 
 	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
 		setupLog.Info("Setting up webhook controller")
-		if err = (&cloudamqpcomv1alpha1.LavinMQ{}).SetupWebhookWithManager(mgr); err != nil {
+		if err = (&cloudamqpcomv1beta1.LavinMQ{}).SetupWebhookWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "LavinMQ")
 			os.Exit(1)
 		}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+	"sigs.k8s.io/yaml"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+	"github.com/cloudamqp/lavinmq-operator/internal/reconciler"
+)
+
+// Namespace of the rendered resources when the manifest doesn't set one.
+const renderNamespace = "default"
+
+// render prints the resources the operator creates for a LavinMQ manifest, without connecting to a cluster. The
+// manifest is defaulted and validated like by the webhooks, except that claims without a storageClassName are
+// kept as is since there's no cluster to look up the default StorageClass in.
+func render(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	file := flags.String("f", "-", "LavinMQ manifest to render, - reads it from stdin.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var manifest []byte
+	var err error
+	if *file == "-" {
+		manifest, err = io.ReadAll(stdin)
+	} else {
+		manifest, err = os.ReadFile(*file)
+	}
+	if err != nil {
+		return err
+	}
+
+	instance, err := decodeLavinMQ(manifest)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := (&cloudamqpcomv1beta1.LavinMQCustomDefaulter{}).Default(ctx, instance); err != nil {
+		return err
+	}
+	if _, err := instance.ValidateCreate(ctx, instance); err != nil {
+		return err
+	}
+
+	c := applyClient{fake.NewClientBuilder().WithScheme(scheme).WithObjects(instance).Build()}
+	resourceReconciler := reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme,
+		Client:   c,
+	}
+	pipeline := resourceReconciler.RunReconcilers(ctx)
+	if len(pipeline.Failures) > 0 {
+		return pipeline.Err()
+	}
+
+	lists := []client.ObjectList{
+		&corev1.ConfigMapList{},
+		&corev1.ServiceList{},
+		&corev1.PersistentVolumeClaimList{},
+		&appsv1.StatefulSetList{},
+	}
+	for _, list := range lists {
+		if err := c.List(ctx, list, client.InNamespace(instance.Namespace)); err != nil {
+			return err
+		}
+		if err := printObjects(stdout, list); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeLavinMQ reads a LavinMQ of any served version and converts it to v1beta1.
+func decodeLavinMQ(manifest []byte) (*cloudamqpcomv1beta1.LavinMQ, error) {
+	obj, _, err := serializer.NewCodecFactory(scheme).UniversalDeserializer().Decode(manifest, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := &cloudamqpcomv1beta1.LavinMQ{}
+	switch lavin := obj.(type) {
+	case *cloudamqpcomv1beta1.LavinMQ:
+		instance = lavin
+	case conversion.Convertible:
+		if err := lavin.ConvertTo(instance); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("expected a LavinMQ but got %s", obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+
+	if instance.Name == "" {
+		return nil, errors.New("metadata.name is required")
+	}
+	if instance.Namespace == "" {
+		instance.Namespace = renderNamespace
+	}
+	// The owner references of the rendered resources point to the instance by UID.
+	instance.UID = types.UID(instance.Name)
+
+	return instance, nil
+}
+
+// printObjects writes the items of list as YAML documents, without the fields set by the API server or left empty.
+func printObjects(out io.Writer, list client.ObjectList) error {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		obj := item.(client.Object)
+		gvk, err := apiutil.GVKForObject(obj, scheme)
+		if err != nil {
+			return err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+		obj.SetResourceVersion("")
+		obj.SetManagedFields(nil)
+
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		unstructured.RemoveNestedField(content, "status")
+		unstructured.RemoveNestedField(content, "metadata", "creationTimestamp")
+		unstructured.RemoveNestedField(content, "spec", "template", "metadata", "creationTimestamp")
+
+		data, err := yaml.Marshal(content)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyClient stores server-side applied objects as they are, the fake client doesn't support apply patches.
+type applyClient struct {
+	client.Client
+}
+
+func (c applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	live := obj.DeepCopyObject().(client.Object)
+	err := c.Get(ctx, client.ObjectKeyFromObject(obj), live)
+	if apierrors.IsNotFound(err) {
+		return c.Create(ctx, obj)
+	}
+	if err != nil {
+		return err
+	}
+
+	obj.SetResourceVersion(live.GetResourceVersion())
+	return c.Update(ctx, obj)
+}
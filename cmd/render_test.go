@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "Update the golden files of the render tests.")
+
+// TestRender compares the resources rendered for each manifest in testdata with its .golden file, run with
+// -update to regenerate them.
+func TestRender(t *testing.T) {
+	manifests, err := filepath.Glob("testdata/*.yaml")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, manifests)
+
+	for _, manifest := range manifests {
+		t.Run(filepath.Base(manifest), func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := render([]string{"-f", manifest}, nil, out)
+			assert.NoErrorf(t, err, "Failed to render %s", manifest)
+
+			golden := strings.TrimSuffix(manifest, ".yaml") + ".golden"
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, out.Bytes(), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), out.String())
+		})
+	}
+}
+
+func TestRenderStdin(t *testing.T) {
+	manifest, err := os.ReadFile("testdata/single.yaml")
+	assert.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	err = render(nil, bytes.NewReader(manifest), out)
+	assert.NoError(t, err)
+
+	expected, err := os.ReadFile("testdata/single.golden")
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), out.String())
+}
+
+func TestRenderInvalid(t *testing.T) {
+	manifest := `apiVersion: cloudamqp.com/v1beta1
+kind: LavinMQ
+metadata:
+  name: invalid
+spec:
+  replicas: 3
+  persistence:
+    dataVolumeClaim:
+      resources:
+        requests:
+          storage: 10Gi
+`
+	err := render(nil, strings.NewReader(manifest), &bytes.Buffer{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "etcd")
+
+	err = render(nil, strings.NewReader("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n"), &bytes.Buffer{})
+	assert.EqualError(t, err, "expected a LavinMQ but got ConfigMap")
+}
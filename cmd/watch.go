@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// watchNamespaces resolves the namespaces the manager watches from a comma separated list and a label selector on
+// namespaces, nil meaning all namespaces. The selector is only evaluated once, namespaces labeled after the
+// manager started are picked up on its next restart.
+func watchNamespaces(ctx context.Context, c client.Reader, list, selector string) ([]string, error) {
+	namespaces := sets.New[string]()
+	for _, namespace := range strings.Split(list, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces.Insert(namespace)
+		}
+	}
+
+	if selector != "" {
+		namespaceSelector, err := labels.Parse(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace selector %q: %w", selector, err)
+		}
+
+		matching := &corev1.NamespaceList{}
+		if err := c.List(ctx, matching, client.MatchingLabelsSelector{Selector: namespaceSelector}); err != nil {
+			return nil, fmt.Errorf("failed to list namespaces matching %q: %w", selector, err)
+		}
+		// Falling back to all namespaces would hand the manager the instances of every tenant.
+		if len(matching.Items) == 0 && namespaces.Len() == 0 {
+			return nil, fmt.Errorf("no namespaces match the selector %q", selector)
+		}
+		for _, namespace := range matching.Items {
+			namespaces.Insert(namespace.Name)
+		}
+	}
+
+	if namespaces.Len() == 0 {
+		return nil, nil
+	}
+
+	return sets.List(namespaces), nil
+}
+
+// cacheOptions limits the cache of the manager, and thereby the watches of the controllers, to namespaces. All
+// namespaces are watched if empty.
+func cacheOptions(namespaces []string) cache.Options {
+	options := cache.Options{}
+	if len(namespaces) == 0 {
+		return options
+	}
+
+	options.DefaultNamespaces = map[string]cache.Config{}
+	for _, namespace := range namespaces {
+		options.DefaultNamespaces[namespace] = cache.Config{}
+	}
+
+	return options
+}
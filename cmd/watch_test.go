@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func namespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestWatchNamespaces(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		namespace("team-a", map[string]string{"tenant-group": "a"}),
+		namespace("team-b", map[string]string{"tenant-group": "a"}),
+		namespace("team-c", map[string]string{"tenant-group": "c"}),
+	).Build()
+	ctx := context.Background()
+
+	namespaces, err := watchNamespaces(ctx, c, "", "")
+	assert.NoError(t, err)
+	assert.Nil(t, namespaces, "Expected all namespaces to be watched")
+
+	namespaces, err = watchNamespaces(ctx, c, " team-c, team-a,,team-a", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team-a", "team-c"}, namespaces)
+
+	namespaces, err = watchNamespaces(ctx, c, "", "tenant-group=a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team-a", "team-b"}, namespaces)
+
+	namespaces, err = watchNamespaces(ctx, c, "team-c", "tenant-group in (a)")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team-a", "team-b", "team-c"}, namespaces)
+
+	_, err = watchNamespaces(ctx, c, "", "tenant-group=x")
+	assert.EqualError(t, err, `no namespaces match the selector "tenant-group=x"`)
+
+	_, err = watchNamespaces(ctx, c, "", "tenant-group in a")
+	assert.ErrorContains(t, err, "invalid namespace selector")
+}
+
+func TestCacheOptions(t *testing.T) {
+	assert.Nil(t, cacheOptions(nil).DefaultNamespaces)
+	assert.Equal(t, map[string]cache.Config{"team-a": {}, "team-b": {}}, cacheOptions([]string{"team-a", "team-b"}).DefaultNamespaces)
+}
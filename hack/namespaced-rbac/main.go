@@ -0,0 +1,158 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command namespaced-rbac turns the manager ClusterRole into a Role and RoleBinding per watched namespace, for
+// managers started with --watch-namespaces. Rules on cluster scoped resources are kept in a ClusterRole.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Resources of the manager ClusterRole that aren't namespaced, and thereby can't be granted by a Role.
+var clusterScopedResources = []string{"namespaces", "storageclasses"}
+
+func main() {
+	role := flag.String("role", "config/rbac/role.yaml", "Manager ClusterRole to derive the namespaced roles from.")
+	namespaces := flag.String("namespaces", "", "Comma separated namespaces the manager watches.")
+	namePrefix := flag.String("name-prefix", "lavinmq-operator-", "Prefix of the generated roles and bindings.")
+	serviceAccount := flag.String("service-account", "lavinmq-operator-controller-manager",
+		"Service account of the manager.")
+	serviceAccountNamespace := flag.String("service-account-namespace", "lavinmq-operator-system",
+		"Namespace the manager runs in.")
+	flag.Parse()
+
+	clusterRole, err := readClusterRole(*role)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	subject := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: *serviceAccount, Namespace: *serviceAccountNamespace}
+	if err := write(os.Stdout, namespacedRBAC(clusterRole, splitNamespaces(*namespaces), *namePrefix, subject)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func readClusterRole(path string) (*rbacv1.ClusterRole, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterRole := &rbacv1.ClusterRole{}
+	if err := yaml.UnmarshalStrict(content, clusterRole); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return clusterRole, nil
+}
+
+func splitNamespaces(list string) []string {
+	namespaces := []string{}
+	for _, namespace := range strings.Split(list, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	return namespaces
+}
+
+// namespacedRBAC returns a Role and RoleBinding per namespace with the namespaced rules of clusterRole, followed by
+// a ClusterRole and ClusterRoleBinding with the rest.
+func namespacedRBAC(clusterRole *rbacv1.ClusterRole, namespaces []string, namePrefix string, subject rbacv1.Subject) []any {
+	namespaced := []rbacv1.PolicyRule{}
+	clusterScoped := []rbacv1.PolicyRule{}
+	for _, rule := range clusterRole.Rules {
+		namespacedRule := rule.DeepCopy()
+		namespacedRule.Resources = slices.DeleteFunc(namespacedRule.Resources, isClusterScoped)
+		if len(namespacedRule.Resources) > 0 {
+			namespaced = append(namespaced, *namespacedRule)
+		}
+
+		clusterScopedRule := rule.DeepCopy()
+		clusterScopedRule.Resources = slices.DeleteFunc(clusterScopedRule.Resources, func(resource string) bool {
+			return !isClusterScoped(resource)
+		})
+		if len(clusterScopedRule.Resources) > 0 {
+			clusterScoped = append(clusterScoped, *clusterScopedRule)
+		}
+	}
+
+	roleName := namePrefix + clusterRole.Name
+	objects := []any{}
+	for _, namespace := range namespaces {
+		objects = append(objects,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+				ObjectMeta: metav1.ObjectMeta{Name: roleName, Namespace: namespace},
+				Rules:      namespaced,
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: roleName + "binding", Namespace: namespace},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: roleName},
+				Subjects:   []rbacv1.Subject{subject},
+			},
+		)
+	}
+
+	clusterRoleName := namePrefix + "manager-cluster-role"
+	return append(objects,
+		&rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+			ObjectMeta: metav1.ObjectMeta{Name: clusterRoleName},
+			Rules:      clusterScoped,
+		},
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: clusterRoleName + "binding"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: clusterRoleName},
+			Subjects:   []rbacv1.Subject{subject},
+		},
+	)
+}
+
+func isClusterScoped(resource string) bool {
+	return slices.Contains(clusterScopedResources, resource)
+}
+
+func write(out io.Writer, objects []any) error {
+	for _, object := range objects {
+		content, err := yaml.Marshal(object)
+		if err != nil {
+			return err
+		}
+		// An unset creation timestamp serializes as null.
+		content = []byte(strings.ReplaceAll(string(content), "  creationTimestamp: null\n", ""))
+		if _, err := fmt.Fprintf(out, "---\n%s", content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -20,12 +20,17 @@ import (
 	"context"
 	"fmt"
 
-	cloudamqpcomv1alpha1 "github.com/cloudamqp/lavinmq-operator/api/v1alpha1"
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+	"github.com/cloudamqp/lavinmq-operator/internal/lavinmq"
+	"github.com/cloudamqp/lavinmq-operator/internal/metrics"
 	"github.com/cloudamqp/lavinmq-operator/internal/reconciler"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -37,8 +42,14 @@ import (
 const (
 	// typeAvailableLavinMQ represents the status of the StatefulSet reconciliation
 	typeAvailableLavinMQ = "Available"
-	// typeDegradedLavinMQ represents the status used when the custom resource is deleted and the finalizer operations are yet to occur.
+	// typeDegradedLavinMQ is true when one or more of the owned resources failed to reconcile.
 	typeDegradedLavinMQ = "Degraded"
+	// typePausedLavinMQ is true while reconciliation is paused by the PausedAnnotation.
+	typePausedLavinMQ = "Paused"
+
+	reasonReconciled      = "Reconciled"
+	reasonReconcileFailed = "ReconcileFailed"
+	reasonPaused          = "PausedByAnnotation"
 )
 
 // LavinMQReconciler reconciles a LavinMQ object
@@ -46,6 +57,8 @@ type LavinMQReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// Reloader makes the pods reload their config when only reloadable keys changed.
+	Reloader lavinmq.ConfigReloader
 }
 
 // +kubebuilder:rbac:groups=cloudamqp.com,resources=lavinmqs,verbs=get;list;watch;create;update;patch;delete
@@ -55,6 +68,10 @@ type LavinMQReconciler struct {
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods/exec,verbs=create
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -70,11 +87,12 @@ func (r *LavinMQReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	logger.Info(fmt.Sprintf("Reconciling LavinMQ %s\n", req.NamespacedName))
 
-	instance := &cloudamqpcomv1alpha1.LavinMQ{}
+	instance := &cloudamqpcomv1beta1.LavinMQ{}
 	err := r.Get(ctx, req.NamespacedName, instance)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			logger.Info("LavinMQ not found, either deleted or never created")
+			metrics.DeleteCluster(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 
@@ -83,32 +101,113 @@ func (r *LavinMQReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	}
 
 	logger.Info("LavinMQ found", "name", instance.Name)
+	if instance.Annotations[cloudamqpcomv1beta1.PausedAnnotation] == "true" {
+		logger.Info("Reconciliation paused", "annotation", cloudamqpcomv1beta1.PausedAnnotation)
+		return ctrl.Result{}, r.pause(ctx, instance)
+	}
+
 	resourceReconciler := reconciler.ResourceReconciler{
 		Instance: instance,
 		Scheme:   r.Scheme,
 		Logger:   logger,
 		Client:   r.Client,
+		Recorder: r.Recorder,
+		Reloader: r.Reloader,
 	}
 
-	reconcilers := resourceReconciler.Reconcilers()
+	// Reconcilers report observed state, like volume expansion progress, directly in the instance status.
+	observed := instance.Status.DeepCopy()
+	pipeline := resourceReconciler.RunReconcilers(ctx)
 
-	for _, reconciler := range reconcilers {
-		_, err := reconciler.Reconcile(ctx)
-		if err != nil {
-			logger.Error(err, "Failed to reconcile resource", "name", reconciler.Name())
-			return ctrl.Result{}, err
-		}
+	if err := r.updateStatus(ctx, instance, observed, &pipeline); err != nil {
+		logger.Error(err, "Failed to update LavinMQ status")
+		return ctrl.Result{}, err
+	}
+
+	if len(pipeline.Failures) > 0 {
+		metrics.SetClusterPhase(instance.Namespace, instance.Name, metrics.PhaseDegraded)
+		return ctrl.Result{}, pipeline.Err()
 	}
 
 	logger.Info("Updated resources for LavinMQ")
+	metrics.SetClusterPhase(instance.Namespace, instance.Name, metrics.PhaseAvailable)
+	metrics.LastSuccessfulReconcile.WithLabelValues(instance.Namespace, instance.Name).SetToCurrentTime()
+
+	return pipeline.Result, nil
+}
+
+// updateStatus reflects the outcome of the reconcile pipeline in the Available and Degraded conditions
+// and records the latest drift correction. The status is only written if it differs from previous.
+func (r *LavinMQReconciler) updateStatus(ctx context.Context, instance *cloudamqpcomv1beta1.LavinMQ,
+	previous *cloudamqpcomv1beta1.LavinMQStatus, pipeline *reconciler.PipelineResult) error {
+	available := metav1.Condition{
+		Type:               typeAvailableLavinMQ,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonReconciled,
+		Message:            "All resources reconciled",
+		ObservedGeneration: instance.Generation,
+	}
+	degraded := metav1.Condition{
+		Type:               typeDegradedLavinMQ,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonReconciled,
+		Message:            "All resources reconciled",
+		ObservedGeneration: instance.Generation,
+	}
+
+	if len(pipeline.Failures) > 0 {
+		available.Status = metav1.ConditionFalse
+		available.Reason = reasonReconcileFailed
+		available.Message = "Failed to reconcile one or more resources"
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = reasonReconcileFailed
+		degraded.Message = pipeline.Message()
+	}
+
+	meta.SetStatusCondition(&instance.Status.Conditions, available)
+	meta.SetStatusCondition(&instance.Status.Conditions, degraded)
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               typePausedLavinMQ,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonReconciled,
+		Message:            "Reconciliation is not paused",
+		ObservedGeneration: instance.Generation,
+	})
+	if len(pipeline.DriftCorrections) > 0 {
+		instance.Status.LastDriftCorrection = &pipeline.DriftCorrections[len(pipeline.DriftCorrections)-1]
+	}
+	if equality.Semantic.DeepEqual(previous, &instance.Status) {
+		return nil
+	}
+
+	return r.Status().Update(ctx, instance)
+}
+
+// pause sets the Paused condition, the owned resources are left as they are until the PausedAnnotation is removed.
+func (r *LavinMQReconciler) pause(ctx context.Context, instance *cloudamqpcomv1beta1.LavinMQ) error {
+	changed := meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               typePausedLavinMQ,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonPaused,
+		Message:            fmt.Sprintf("Reconciliation paused by the annotation %s", cloudamqpcomv1beta1.PausedAnnotation),
+		ObservedGeneration: instance.Generation,
+	})
+	if !changed {
+		return nil
+	}
+
+	if err := r.Status().Update(ctx, instance); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to update LavinMQ status")
+		return err
+	}
 
-	return ctrl.Result{}, nil
+	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *LavinMQReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&cloudamqpcomv1alpha1.LavinMQ{}).
+		For(&cloudamqpcomv1beta1.LavinMQ{}).
 		Owns(&appsv1.StatefulSet{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Service{}).
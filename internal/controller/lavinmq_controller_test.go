@@ -22,13 +22,19 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
 
-	cloudamqpcomv1alpha1 "github.com/cloudamqp/lavinmq-operator/api/v1alpha1"
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
 	testutils "github.com/cloudamqp/lavinmq-operator/internal/test_utils"
 )
 
@@ -58,7 +64,7 @@ func TestDefaultLavinMQ(t *testing.T) {
 	err := k8sClient.Create(t.Context(), lavinmq)
 	assert.NoErrorf(t, err, "Failed to create LavinMQ resource")
 
-	resource := &cloudamqpcomv1alpha1.LavinMQ{}
+	resource := &cloudamqpcomv1beta1.LavinMQ{}
 	err = k8sClient.Get(t.Context(), types.NamespacedName{
 		Name:      lavinmq.Name,
 		Namespace: lavinmq.Namespace,
@@ -83,7 +89,7 @@ func TestCreatingCustomLavinMQ(t *testing.T) {
 
 		assert.NoErrorf(t, err, "Failed to create LavinMQ resource")
 
-		resource := &cloudamqpcomv1alpha1.LavinMQ{}
+		resource := &cloudamqpcomv1beta1.LavinMQ{}
 		err = k8sClient.Get(t.Context(), types.NamespacedName{
 			Name:      lavinmq.Name,
 			Namespace: lavinmq.Namespace,
@@ -103,7 +109,7 @@ func TestCreatingCustomLavinMQ(t *testing.T) {
 
 		assert.NoErrorf(t, err, "Failed to create LavinMQ resource")
 
-		resource := &cloudamqpcomv1alpha1.LavinMQ{}
+		resource := &cloudamqpcomv1beta1.LavinMQ{}
 		err = k8sClient.Get(t.Context(), types.NamespacedName{
 			Name:      lavinmq.Name,
 			Namespace: lavinmq.Namespace,
@@ -121,11 +127,12 @@ func TestCreatingCustomLavinMQ(t *testing.T) {
 		defer cleanupResources(t, lavinmq)
 
 		lavinmq.Spec.Replicas = 3
+		lavinmq.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
 		err := k8sClient.Create(t.Context(), lavinmq)
 
 		assert.NoErrorf(t, err, "Failed to create LavinMQ resource")
 
-		resource := &cloudamqpcomv1alpha1.LavinMQ{}
+		resource := &cloudamqpcomv1beta1.LavinMQ{}
 		err = k8sClient.Get(t.Context(), types.NamespacedName{
 			Name:      lavinmq.Name,
 			Namespace: lavinmq.Namespace,
@@ -160,6 +167,13 @@ func TestUpdatingLavinMQ(t *testing.T) {
 
 		assert.NoErrorf(t, err, "Failed to reconcile")
 
+		// Reconcile updates the status, fetch the latest version before updating
+		err = k8sClient.Get(t.Context(), types.NamespacedName{
+			Name:      lavinmq.Name,
+			Namespace: lavinmq.Namespace,
+		}, lavinmq)
+		assert.NoErrorf(t, err, "Failed to get LavinMQ resource")
+
 		lavinmq.Spec.Config.Amqp.Port = 1337
 		err = k8sClient.Update(t.Context(), lavinmq)
 		assert.NoErrorf(t, err, "Failed to update LavinMQ resource")
@@ -214,6 +228,13 @@ func TestUpdatingLavinMQ(t *testing.T) {
 
 		assert.NoErrorf(t, err, "Failed to reconcile")
 
+		// Reconcile updates the status, fetch the latest version before updating
+		err = k8sClient.Get(t.Context(), types.NamespacedName{
+			Name:      lavinmq.Name,
+			Namespace: lavinmq.Namespace,
+		}, lavinmq)
+		assert.NoErrorf(t, err, "Failed to get LavinMQ resource")
+
 		lavinmq.Spec.Image = "cloudamqp/lavinmq:2.4.1"
 		err = k8sClient.Update(t.Context(), lavinmq)
 		assert.NoErrorf(t, err, "Failed to update LavinMQ resource")
@@ -240,7 +261,100 @@ func TestUpdatingLavinMQ(t *testing.T) {
 
 }
 
-func setupResources(t *testing.T) (*LavinMQReconciler, *cloudamqpcomv1alpha1.LavinMQ) {
+func TestReconcileConditions(t *testing.T) {
+	t.Parallel()
+	reconciler, lavinmq := setupResources(t)
+
+	defer cleanupResources(t, lavinmq)
+
+	err := k8sClient.Create(t.Context(), lavinmq)
+	assert.NoErrorf(t, err, "Failed to create LavinMQ resource")
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      lavinmq.Name,
+			Namespace: lavinmq.Namespace,
+		},
+	}
+
+	_, err = reconciler.Reconcile(t.Context(), request)
+	assert.NoErrorf(t, err, "Failed to reconcile")
+
+	err = k8sClient.Get(t.Context(), request.NamespacedName, lavinmq)
+	assert.NoErrorf(t, err, "Failed to get LavinMQ resource")
+	assert.True(t, meta.IsStatusConditionTrue(lavinmq.Status.Conditions, typeAvailableLavinMQ))
+	assert.True(t, meta.IsStatusConditionFalse(lavinmq.Status.Conditions, typeDegradedLavinMQ))
+
+	t.Log("Shrinking the volume fails the PVC reconciler")
+	lavinmq.Spec.Persistence.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("5Gi")
+	lavinmq.Spec.Image = "cloudamqp/lavinmq:2.4.2"
+	err = k8sClient.Update(t.Context(), lavinmq)
+	assert.NoErrorf(t, err, "Failed to update LavinMQ resource")
+
+	_, err = reconciler.Reconcile(t.Context(), request)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "pvc: volume size decreased, not supported")
+
+	err = k8sClient.Get(t.Context(), request.NamespacedName, lavinmq)
+	assert.NoErrorf(t, err, "Failed to get LavinMQ resource")
+	assert.True(t, meta.IsStatusConditionFalse(lavinmq.Status.Conditions, typeAvailableLavinMQ))
+	degraded := meta.FindStatusCondition(lavinmq.Status.Conditions, typeDegradedLavinMQ)
+	assert.NotNil(t, degraded)
+	assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+	assert.Contains(t, degraded.Message, "pvc: volume size decreased, not supported")
+
+	t.Log("Independent resources are still reconciled")
+	sts := &appsv1.StatefulSet{}
+	err = k8sClient.Get(t.Context(), request.NamespacedName, sts)
+	assert.NoErrorf(t, err, "Failed to get StatefulSet")
+	assert.Equal(t, "cloudamqp/lavinmq:2.4.2", sts.Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestPausedLavinMQ(t *testing.T) {
+	t.Parallel()
+	reconciler, lavinmq := setupResources(t)
+
+	defer cleanupResources(t, lavinmq)
+
+	lavinmq.Annotations = map[string]string{cloudamqpcomv1beta1.PausedAnnotation: "true"}
+	err := k8sClient.Create(t.Context(), lavinmq)
+	assert.NoErrorf(t, err, "Failed to create LavinMQ resource")
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      lavinmq.Name,
+			Namespace: lavinmq.Namespace,
+		},
+	}
+
+	_, err = reconciler.Reconcile(t.Context(), request)
+	assert.NoErrorf(t, err, "Failed to reconcile")
+
+	err = k8sClient.Get(t.Context(), request.NamespacedName, lavinmq)
+	assert.NoErrorf(t, err, "Failed to get LavinMQ resource")
+	assert.True(t, meta.IsStatusConditionTrue(lavinmq.Status.Conditions, typePausedLavinMQ))
+
+	sts := &appsv1.StatefulSet{}
+	err = k8sClient.Get(t.Context(), request.NamespacedName, sts)
+	assert.True(t, apierrors.IsNotFound(err), "StatefulSet should not be created while paused")
+
+	t.Log("Resuming once the annotation is removed")
+	delete(lavinmq.Annotations, cloudamqpcomv1beta1.PausedAnnotation)
+	err = k8sClient.Update(t.Context(), lavinmq)
+	assert.NoErrorf(t, err, "Failed to update LavinMQ resource")
+
+	_, err = reconciler.Reconcile(t.Context(), request)
+	assert.NoErrorf(t, err, "Failed to reconcile")
+
+	err = k8sClient.Get(t.Context(), request.NamespacedName, lavinmq)
+	assert.NoErrorf(t, err, "Failed to get LavinMQ resource")
+	assert.True(t, meta.IsStatusConditionFalse(lavinmq.Status.Conditions, typePausedLavinMQ))
+
+	err = k8sClient.Get(t.Context(), request.NamespacedName, sts)
+	assert.NoErrorf(t, err, "Failed to get StatefulSet")
+}
+
+func setupResources(t *testing.T) (*LavinMQReconciler, *cloudamqpcomv1beta1.LavinMQ) {
 	reconciler := &LavinMQReconciler{
 		Client: k8sClient,
 		Scheme: k8sClient.Scheme(),
@@ -252,7 +366,7 @@ func setupResources(t *testing.T) (*LavinMQReconciler, *cloudamqpcomv1alpha1.Lav
 	return reconciler, lavinmq
 }
 
-func cleanupResources(t *testing.T, lavinmq *cloudamqpcomv1alpha1.LavinMQ) {
+func cleanupResources(t *testing.T, lavinmq *cloudamqpcomv1beta1.LavinMQ) {
 	resourceName := lavinmq.Name
 	namespace := lavinmq.Namespace
 
@@ -279,7 +393,7 @@ func cleanupResources(t *testing.T, lavinmq *cloudamqpcomv1alpha1.LavinMQ) {
 	}
 
 	// Clean up LavinMQ
-	resource := &cloudamqpcomv1alpha1.LavinMQ{}
+	resource := &cloudamqpcomv1beta1.LavinMQ{}
 	err = k8sClient.Get(t.Context(), types.NamespacedName{
 		Name:      resourceName,
 		Namespace: namespace,
@@ -292,3 +406,62 @@ func cleanupResources(t *testing.T, lavinmq *cloudamqpcomv1alpha1.LavinMQ) {
 	err = testutils.DeleteNamespace(t.Context(), k8sClient, namespace)
 	assert.NoErrorf(t, err, "Failed to delete namespace")
 }
+
+func TestScaleSubresource(t *testing.T) {
+	t.Parallel()
+	reconciler, lavinmq := setupResources(t)
+
+	defer cleanupResources(t, lavinmq)
+
+	err := k8sClient.Create(t.Context(), lavinmq)
+	assert.NoErrorf(t, err, "Failed to create LavinMQ resource")
+
+	request := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      lavinmq.Name,
+			Namespace: lavinmq.Namespace,
+		},
+	}
+
+	_, err = reconciler.Reconcile(t.Context(), request)
+	assert.NoErrorf(t, err, "Failed to reconcile")
+
+	scale := &autoscalingv1.Scale{}
+	err = k8sClient.SubResource("scale").Get(t.Context(), lavinmq, scale)
+	assert.NoErrorf(t, err, "Failed to get scale")
+	assert.Equal(t, int32(1), scale.Spec.Replicas)
+	assert.Contains(t, scale.Status.Selector, "app.kubernetes.io/instance="+lavinmq.Name)
+
+	t.Log("Rejecting scaling out without an etcd cluster")
+	scale.Spec.Replicas = 3
+	err = k8sClient.SubResource("scale").Update(t.Context(), lavinmq, client.WithSubResourceBody(scale))
+	assert.Truef(t, apierrors.IsInvalid(err), "Expected an invalid error, got %v", err)
+	assert.ErrorContains(t, err, "a provided etcd cluster is required for replication")
+
+	t.Log("Rejecting scaling out a single node that didn't run with etcd")
+	err = k8sClient.Get(t.Context(), request.NamespacedName, lavinmq)
+	assert.NoErrorf(t, err, "Failed to get LavinMQ resource")
+	lavinmq.Spec.Replicas = 3
+	lavinmq.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+	err = k8sClient.Update(t.Context(), lavinmq)
+	assert.ErrorContains(t, err, "first update to run the single node with etcd cluster")
+
+	t.Log("Scaling out once the single node runs with etcd")
+	lavinmq.Spec.Replicas = 1
+	err = k8sClient.Update(t.Context(), lavinmq)
+	assert.NoErrorf(t, err, "Failed to update LavinMQ resource")
+
+	err = k8sClient.SubResource("scale").Get(t.Context(), lavinmq, scale)
+	assert.NoErrorf(t, err, "Failed to get scale")
+	scale.Spec.Replicas = 3
+	err = k8sClient.SubResource("scale").Update(t.Context(), lavinmq, client.WithSubResourceBody(scale))
+	assert.NoErrorf(t, err, "Failed to scale LavinMQ")
+
+	_, err = reconciler.Reconcile(t.Context(), request)
+	assert.NoErrorf(t, err, "Failed to reconcile")
+
+	sts := &appsv1.StatefulSet{}
+	err = k8sClient.Get(t.Context(), request.NamespacedName, sts)
+	assert.NoErrorf(t, err, "Failed to get StatefulSet")
+	assert.Equal(t, int32(3), *sts.Spec.Replicas)
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+	"github.com/cloudamqp/lavinmq-operator/internal/controller/utils"
+	"github.com/cloudamqp/lavinmq-operator/internal/lavinmq"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// How often the VolumeSnapshots are checked until they're ready to use.
+const snapshotRequeueDelay = 10 * time.Second
+
+// Label on the VolumeSnapshots with the name of the LavinMQSnapshot they belong to.
+const snapshotLabel = "lavinmq.cloudamqp.com/snapshot"
+
+// LavinMQSnapshotReconciler takes VolumeSnapshots of the data volumes of a LavinMQ
+type LavinMQSnapshotReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// Leaders finds the leader when only followers are snapshotted, etcd is queried if nil.
+	Leaders lavinmq.LeaderFinder
+}
+
+// +kubebuilder:rbac:groups=cloudamqp.com,resources=lavinmqsnapshots,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cloudamqp.com,resources=lavinmqsnapshots/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;list;watch;create;delete
+
+// Reconcile creates a VolumeSnapshot of every data volume, all at once so they're taken as close in time as
+// possible, and tracks them until they're ready to use. A snapshot that is ready or failed is never changed.
+func (r *LavinMQSnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	snapshot := &cloudamqpcomv1beta1.LavinMQSnapshot{}
+	if err := r.Get(ctx, req.NamespacedName, snapshot); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if snapshot.Status.Phase == cloudamqpcomv1beta1.SnapshotPhaseReady || snapshot.Status.Phase == cloudamqpcomv1beta1.SnapshotPhaseFailed {
+		return ctrl.Result{}, nil
+	}
+
+	previous := snapshot.Status.DeepCopy()
+	result, err := r.takeSnapshots(ctx, snapshot)
+	if err != nil {
+		logger.Error(err, "Failed to snapshot LavinMQ", "lavinmq", snapshot.Spec.LavinMQ)
+		return ctrl.Result{}, err
+	}
+
+	if !equality.Semantic.DeepEqual(previous, &snapshot.Status) {
+		switch snapshot.Status.Phase {
+		case cloudamqpcomv1beta1.SnapshotPhaseReady:
+			r.eventf(snapshot, corev1.EventTypeNormal, "SnapshotReady", "All %d volume snapshots are ready to use",
+				len(snapshot.Status.Volumes))
+		case cloudamqpcomv1beta1.SnapshotPhaseFailed:
+			r.eventf(snapshot, corev1.EventTypeWarning, "SnapshotFailed", "%s", snapshot.Status.Message)
+		}
+
+		if err := r.Status().Update(ctx, snapshot); err != nil {
+			logger.Error(err, "Failed to update LavinMQSnapshot status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return result, nil
+}
+
+// takeSnapshots creates the missing VolumeSnapshots and reflects their state in the status.
+func (r *LavinMQSnapshotReconciler) takeSnapshots(ctx context.Context, snapshot *cloudamqpcomv1beta1.LavinMQSnapshot) (ctrl.Result, error) {
+	if len(snapshot.Status.Volumes) == 0 {
+		pvcs, err := r.volumesToSnapshot(ctx, snapshot)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				r.fail(snapshot, fmt.Sprintf("LavinMQ %s not found", snapshot.Spec.LavinMQ))
+				return ctrl.Result{}, nil
+			}
+			return ctrl.Result{}, err
+		}
+		if pvcs == nil {
+			return ctrl.Result{}, nil
+		}
+
+		for _, pvc := range pvcs {
+			snapshot.Status.Volumes = append(snapshot.Status.Volumes, cloudamqpcomv1beta1.VolumeSnapshotStatus{
+				PersistentVolumeClaim: pvc,
+				VolumeSnapshot:        snapshot.Name + "-" + pvc,
+			})
+		}
+	}
+
+	ready := 0
+	for i := range snapshot.Status.Volumes {
+		volume := &snapshot.Status.Volumes[i]
+		if err := r.syncVolumeSnapshot(ctx, snapshot, volume); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if volume.Error != "" {
+			r.fail(snapshot, fmt.Sprintf("Snapshot of %s failed: %s", volume.PersistentVolumeClaim, volume.Error))
+			return ctrl.Result{}, nil
+		}
+		if volume.ReadyToUse {
+			ready++
+		}
+	}
+
+	if ready < len(snapshot.Status.Volumes) {
+		snapshot.Status.Phase = cloudamqpcomv1beta1.SnapshotPhaseInProgress
+		return ctrl.Result{RequeueAfter: snapshotRequeueDelay}, nil
+	}
+
+	snapshot.Status.Phase = cloudamqpcomv1beta1.SnapshotPhaseReady
+	snapshot.Status.CompletionTime = ptr.To(metav1.Now())
+	return ctrl.Result{}, nil
+}
+
+// volumesToSnapshot returns the data PVCs of the instance, without the leader's if only followers are
+// snapshotted. Returns nil if the snapshot failed.
+func (r *LavinMQSnapshotReconciler) volumesToSnapshot(ctx context.Context, snapshot *cloudamqpcomv1beta1.LavinMQSnapshot) ([]string, error) {
+	instance := &cloudamqpcomv1beta1.LavinMQ{}
+	if err := r.Get(ctx, types.NamespacedName{Name: snapshot.Spec.LavinMQ, Namespace: snapshot.Namespace}, instance); err != nil {
+		return nil, err
+	}
+
+	leader := ""
+	if snapshot.Spec.FollowersOnly {
+		if !instance.Spec.Clustering.Enabled() || instance.Spec.Replicas < 2 {
+			r.fail(snapshot, "followersOnly requires a clustered LavinMQ with followers")
+			return nil, nil
+		}
+
+		var err error
+		if leader, err = r.leaderFinder().Leader(ctx, instance); err != nil {
+			return nil, err
+		}
+	}
+
+	pvcs := []string{}
+	for i := range int(instance.Spec.Replicas) {
+		pod := fmt.Sprintf("%s-%d", instance.Name, i)
+		if pod != leader {
+			pvcs = append(pvcs, "data-"+pod)
+		}
+	}
+
+	return pvcs, nil
+}
+
+// syncVolumeSnapshot creates the VolumeSnapshot of volume if missing and copies its state into volume.
+func (r *LavinMQSnapshotReconciler) syncVolumeSnapshot(ctx context.Context, snapshot *cloudamqpcomv1beta1.LavinMQSnapshot,
+	volume *cloudamqpcomv1beta1.VolumeSnapshotStatus) error {
+	volumeSnapshot := &unstructured.Unstructured{}
+	volumeSnapshot.SetGroupVersionKind(utils.VolumeSnapshotGVK)
+	err := r.Get(ctx, types.NamespacedName{Name: volume.VolumeSnapshot, Namespace: snapshot.Namespace}, volumeSnapshot)
+	if apierrors.IsNotFound(err) {
+		volumeSnapshot = r.newVolumeSnapshot(snapshot, volume)
+		if err := ctrl.SetControllerReference(snapshot, volumeSnapshot, r.Scheme); err != nil {
+			return err
+		}
+		if err := r.Create(ctx, volumeSnapshot); err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("Created VolumeSnapshot", "name", volume.VolumeSnapshot, "pvc", volume.PersistentVolumeClaim)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	volume.ReadyToUse, _, _ = unstructured.NestedBool(volumeSnapshot.Object, "status", "readyToUse")
+	volume.Error, _, _ = unstructured.NestedString(volumeSnapshot.Object, "status", "error", "message")
+	return nil
+}
+
+func (r *LavinMQSnapshotReconciler) newVolumeSnapshot(snapshot *cloudamqpcomv1beta1.LavinMQSnapshot,
+	volume *cloudamqpcomv1beta1.VolumeSnapshotStatus) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": volume.PersistentVolumeClaim,
+		},
+	}
+	if snapshot.Spec.VolumeSnapshotClassName != nil {
+		spec["volumeSnapshotClassName"] = *snapshot.Spec.VolumeSnapshotClassName
+	}
+
+	volumeSnapshot := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	volumeSnapshot.SetGroupVersionKind(utils.VolumeSnapshotGVK)
+	volumeSnapshot.SetName(volume.VolumeSnapshot)
+	volumeSnapshot.SetNamespace(snapshot.Namespace)
+	volumeSnapshot.SetLabels(map[string]string{snapshotLabel: snapshot.Name})
+
+	return volumeSnapshot
+}
+
+func (r *LavinMQSnapshotReconciler) fail(snapshot *cloudamqpcomv1beta1.LavinMQSnapshot, message string) {
+	snapshot.Status.Phase = cloudamqpcomv1beta1.SnapshotPhaseFailed
+	snapshot.Status.Message = message
+}
+
+// eventf records an event on the snapshot, it's a no-op when no recorder is configured.
+func (r *LavinMQSnapshotReconciler) eventf(snapshot *cloudamqpcomv1beta1.LavinMQSnapshot, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+
+	r.Recorder.Eventf(snapshot, eventType, reason, messageFmt, args...)
+}
+
+// leaderFinder returns the configured LeaderFinder or one querying etcd.
+func (r *LavinMQSnapshotReconciler) leaderFinder() lavinmq.LeaderFinder {
+	if r.Leaders == nil {
+		return &lavinmq.EtcdLeaderFinder{}
+	}
+
+	return r.Leaders
+}
+
+// SetupWithManager sets up the controller with the Manager. The VolumeSnapshots aren't watched, as their CRD
+// may not be installed, but polled while in progress.
+func (r *LavinMQSnapshotReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&cloudamqpcomv1beta1.LavinMQSnapshot{}).
+		Complete(r)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+	"github.com/cloudamqp/lavinmq-operator/internal/controller/utils"
+	testutils "github.com/cloudamqp/lavinmq-operator/internal/test_utils"
+)
+
+type staticLeader string
+
+func (l staticLeader) Leader(context.Context, *cloudamqpcomv1beta1.LavinMQ) (string, error) {
+	return string(l), nil
+}
+
+func TestSnapshotLavinMQ(t *testing.T) {
+	t.Parallel()
+	lavinmq := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{Replicas: ptr.To(int32(3))})
+	lavinmq.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+	err := testutils.CreateNamespace(t.Context(), k8sClient, lavinmq.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, lavinmq.Namespace)
+
+	assert.NoError(t, k8sClient.Create(t.Context(), lavinmq))
+
+	snapshot := &cloudamqpcomv1beta1.LavinMQSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: lavinmq.Namespace},
+		Spec: cloudamqpcomv1beta1.LavinMQSnapshotSpec{
+			LavinMQ:                 lavinmq.Name,
+			VolumeSnapshotClassName: ptr.To("csi-snapclass"),
+			FollowersOnly:           true,
+		},
+	}
+	assert.NoError(t, k8sClient.Create(t.Context(), snapshot))
+
+	reconciler := &LavinMQSnapshotReconciler{
+		Client:  k8sClient,
+		Scheme:  k8sClient.Scheme(),
+		Leaders: staticLeader(lavinmq.Name + "-0"),
+	}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: snapshot.Name, Namespace: snapshot.Namespace}}
+
+	result, err := reconciler.Reconcile(t.Context(), request)
+	assert.NoErrorf(t, err, "Failed to reconcile")
+	assert.NotZero(t, result.RequeueAfter)
+
+	assert.NoError(t, k8sClient.Get(t.Context(), request.NamespacedName, snapshot))
+	assert.Equal(t, cloudamqpcomv1beta1.SnapshotPhaseInProgress, snapshot.Status.Phase)
+	assert.Len(t, snapshot.Status.Volumes, 2, "The leader's volume should be skipped")
+
+	t.Log("Marking the VolumeSnapshots of the followers ready to use")
+	for _, pvc := range []string{"data-" + lavinmq.Name + "-1", "data-" + lavinmq.Name + "-2"} {
+		volumeSnapshot := &unstructured.Unstructured{}
+		volumeSnapshot.SetGroupVersionKind(utils.VolumeSnapshotGVK)
+		key := types.NamespacedName{Name: snapshot.Name + "-" + pvc, Namespace: snapshot.Namespace}
+		assert.NoError(t, k8sClient.Get(t.Context(), key, volumeSnapshot))
+
+		source, _, _ := unstructured.NestedString(volumeSnapshot.Object, "spec", "source", "persistentVolumeClaimName")
+		assert.Equal(t, pvc, source)
+		class, _, _ := unstructured.NestedString(volumeSnapshot.Object, "spec", "volumeSnapshotClassName")
+		assert.Equal(t, "csi-snapclass", class)
+		assert.Equal(t, snapshot.Name, volumeSnapshot.GetLabels()["lavinmq.cloudamqp.com/snapshot"])
+
+		assert.NoError(t, unstructured.SetNestedField(volumeSnapshot.Object, true, "status", "readyToUse"))
+		assert.NoError(t, k8sClient.Status().Update(t.Context(), volumeSnapshot))
+	}
+
+	result, err = reconciler.Reconcile(t.Context(), request)
+	assert.NoErrorf(t, err, "Failed to reconcile")
+	assert.Zero(t, result.RequeueAfter)
+
+	assert.NoError(t, k8sClient.Get(t.Context(), request.NamespacedName, snapshot))
+	assert.Equal(t, cloudamqpcomv1beta1.SnapshotPhaseReady, snapshot.Status.Phase)
+	assert.NotNil(t, snapshot.Status.CompletionTime)
+	for _, volume := range snapshot.Status.Volumes {
+		assert.True(t, volume.ReadyToUse)
+	}
+}
+
+func TestSnapshotMissingLavinMQ(t *testing.T) {
+	t.Parallel()
+	namespace := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{}).Namespace
+	err := testutils.CreateNamespace(t.Context(), k8sClient, namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, namespace)
+
+	snapshot := &cloudamqpcomv1beta1.LavinMQSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: namespace},
+		Spec:       cloudamqpcomv1beta1.LavinMQSnapshotSpec{LavinMQ: "missing"},
+	}
+	assert.NoError(t, k8sClient.Create(t.Context(), snapshot))
+
+	reconciler := &LavinMQSnapshotReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: snapshot.Name, Namespace: snapshot.Namespace}}
+	_, err = reconciler.Reconcile(t.Context(), request)
+	assert.NoErrorf(t, err, "Failed to reconcile")
+
+	assert.NoError(t, k8sClient.Get(t.Context(), request.NamespacedName, snapshot))
+	assert.Equal(t, cloudamqpcomv1beta1.SnapshotPhaseFailed, snapshot.Status.Phase)
+	assert.Equal(t, "LavinMQ missing not found", snapshot.Status.Message)
+}
@@ -0,0 +1,9 @@
+package utils
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// VolumeSnapshotGVK is the kind of the CSI snapshots. They're handled as unstructured objects so the operator
+// doesn't depend on the external-snapshotter client, nor on the CRDs being installed unless snapshots are used.
+var VolumeSnapshotGVK = schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshot"}
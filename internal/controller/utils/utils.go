@@ -1,19 +1,35 @@
 package utils
 
 import (
-	cloudamqpcomv1alpha1 "github.com/cloudamqp/lavinmq-operator/api/v1alpha1"
+	"maps"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
 )
 
-func LabelsForLavinMQ(instance *cloudamqpcomv1alpha1.LavinMQ) map[string]string {
-	labels := map[string]string{
-		"app.kubernetes.io/name":       "lavinmq-operator",
-		"app.kubernetes.io/managed-by": "LavinMQController",
-	}
+// ComponentLabel tells the LavinMQ pods apart from other pods of the same instance, e.g. ones run by tooling.
+const ComponentLabel = "app.kubernetes.io/component"
+
+// Component is the value of ComponentLabel on the LavinMQ pods.
+const Component = "broker"
 
-	// Append instance labels
-	for k, v := range instance.Labels {
-		labels[k] = v
+// SelectorLabels returns the labels the StatefulSet and Service select the pods of instance by, unique per
+// instance in a namespace. They don't include the labels of the LavinMQ, as the selector of a StatefulSet
+// can't be changed.
+func SelectorLabels(instance *cloudamqpcomv1beta1.LavinMQ) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":          "lavinmq-operator",
+		"app.kubernetes.io/managed-by":    "LavinMQController",
+		cloudamqpcomv1beta1.InstanceLabel: instance.Name,
+		ComponentLabel:                    Component,
 	}
+}
+
+// LabelsForLavinMQ returns the labels of the resources created for instance, the labels of the LavinMQ
+// with the selector labels on top.
+func LabelsForLavinMQ(instance *cloudamqpcomv1beta1.LavinMQ) map[string]string {
+	labels := map[string]string{}
+	maps.Copy(labels, instance.Labels)
+	maps.Copy(labels, SelectorLabels(instance))
 
 	return labels
 }
@@ -0,0 +1,161 @@
+// Package lavinmq talks to the LavinMQ nodes, and the etcd cluster they use, of a LavinMQ instance.
+package lavinmq
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+)
+
+// ErrNoLeader is returned when no node of the instance currently holds the leadership.
+var ErrNoLeader = errors.New("no leader elected")
+
+// LeaderFinder looks up which pod leads a clustered instance.
+type LeaderFinder interface {
+	// Leader returns the name of the pod that is the current leader.
+	Leader(ctx context.Context, instance *cloudamqpcomv1beta1.LavinMQ) (string, error)
+}
+
+// EtcdLeaderFinder finds the leader through the gRPC gateway of the etcd cluster the nodes campaign in.
+// Every node campaigns by putting its advertised URI under <etcd_prefix>/leader/, the leader is the node
+// with the oldest key.
+type EtcdLeaderFinder struct {
+	// Client used for the requests to etcd, a client with a 10 second timeout is used if nil.
+	Client *http.Client
+}
+
+type rangeRequest struct {
+	Key        string `json:"key"`
+	RangeEnd   string `json:"range_end"`
+	Limit      int64  `json:"limit"`
+	SortOrder  string `json:"sort_order"`
+	SortTarget string `json:"sort_target"`
+}
+
+type rangeResponse struct {
+	Kvs []struct {
+		Value string `json:"value"`
+	} `json:"kvs"`
+}
+
+// Leader queries the etcd endpoints of the instance in order until one responds.
+func (f *EtcdLeaderFinder) Leader(ctx context.Context, instance *cloudamqpcomv1beta1.LavinMQ) (string, error) {
+	if !instance.Spec.Clustering.Enabled() {
+		return "", fmt.Errorf("clustering not enabled for %s", instance.Name)
+	}
+
+	client := f.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	body, err := LeaderRangeRequest(instance)
+	if err != nil {
+		return "", err
+	}
+
+	errs := []error{}
+	for _, endpoint := range instance.Spec.Clustering.EtcdEndpoints {
+		leader, err := queryLeader(ctx, client, EndpointURL(endpoint), body)
+		if err == nil || errors.Is(err, ErrNoLeader) {
+			return leader, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+	}
+
+	return "", fmt.Errorf("querying etcd for the leader: %w", errors.Join(errs...))
+}
+
+// LeaderRangeRequest returns the body of the request to the /v3/kv/range endpoint of the etcd gRPC gateway for the
+// oldest campaign key, the one of the leader.
+func LeaderRangeRequest(instance *cloudamqpcomv1beta1.LavinMQ) ([]byte, error) {
+	// The etcd_prefix of the nodes is the instance name, see the config reconciler.
+	prefix := instance.Name + "/leader/"
+	return json.Marshal(rangeRequest{
+		Key:        base64.StdEncoding.EncodeToString([]byte(prefix)),
+		RangeEnd:   base64.StdEncoding.EncodeToString(prefixEnd(prefix)),
+		Limit:      1,
+		SortOrder:  "ASCEND",
+		SortTarget: "CREATE",
+	})
+}
+
+// EndpointURL returns the URL of an etcd endpoint, which defaults to plain HTTP when given as host:port.
+func EndpointURL(endpoint string) string {
+	if !strings.Contains(endpoint, "://") {
+		return "http://" + endpoint
+	}
+
+	return endpoint
+}
+
+func queryLeader(ctx context.Context, client *http.Client, endpoint string, body []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/v3/kv/range", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	result := rangeResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if len(result.Kvs) == 0 {
+		return "", ErrNoLeader
+	}
+
+	value, err := base64.StdEncoding.DecodeString(result.Kvs[0].Value)
+	if err != nil {
+		return "", err
+	}
+
+	return podFromURI(string(value))
+}
+
+// podFromURI extracts the pod name from an advertised clustering URI,
+// e.g. tcp://lavinmq-0.lavinmq.default.svc.cluster.local:5679.
+func podFromURI(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("parsing leader URI %q: %w", uri, err)
+	}
+
+	pod, _, _ := strings.Cut(parsed.Hostname(), ".")
+	if pod == "" {
+		return "", fmt.Errorf("no pod in leader URI %q", uri)
+	}
+
+	return pod, nil
+}
+
+// prefixEnd returns the range end matching all keys starting with prefix.
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+
+	return []byte{0}
+}
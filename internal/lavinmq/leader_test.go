@@ -0,0 +1,71 @@
+package lavinmq
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+)
+
+func clusteredInstance(endpoints ...string) *cloudamqpcomv1beta1.LavinMQ {
+	return &cloudamqpcomv1beta1.LavinMQ{
+		ObjectMeta: metav1.ObjectMeta{Name: "lavinmq", Namespace: "default"},
+		Spec: cloudamqpcomv1beta1.LavinMQSpec{
+			Clustering: cloudamqpcomv1beta1.ClusteringSpec{EtcdEndpoints: endpoints},
+		},
+	}
+}
+
+func TestLeader(t *testing.T) {
+	t.Parallel()
+	etcd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/kv/range", r.URL.Path)
+
+		req := rangeRequest{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		key, _ := base64.StdEncoding.DecodeString(req.Key)
+		rangeEnd, _ := base64.StdEncoding.DecodeString(req.RangeEnd)
+		assert.Equal(t, "lavinmq/leader/", string(key))
+		assert.Equal(t, "lavinmq/leader0", string(rangeEnd))
+		assert.Equal(t, "CREATE", req.SortTarget)
+
+		value := base64.StdEncoding.EncodeToString([]byte("tcp://lavinmq-1.lavinmq.default.svc.cluster.local:5679"))
+		_, _ = w.Write([]byte(`{"kvs":[{"value":"` + value + `"}]}`))
+	}))
+	defer etcd.Close()
+
+	finder := &EtcdLeaderFinder{}
+	leader, err := finder.Leader(t.Context(), clusteredInstance(strings.TrimPrefix(etcd.URL, "http://")))
+	assert.NoError(t, err)
+	assert.Equal(t, "lavinmq-1", leader)
+}
+
+func TestLeaderTriesNextEndpoint(t *testing.T) {
+	t.Parallel()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"header":{}}`))
+	}))
+	defer empty.Close()
+
+	finder := &EtcdLeaderFinder{}
+	_, err := finder.Leader(t.Context(), clusteredInstance(down.URL, empty.URL))
+	assert.ErrorIs(t, err, ErrNoLeader)
+}
+
+func TestLeaderWithoutClustering(t *testing.T) {
+	t.Parallel()
+	finder := &EtcdLeaderFinder{}
+	_, err := finder.Leader(t.Context(), clusteredInstance())
+	assert.Error(t, err)
+}
@@ -0,0 +1,79 @@
+package lavinmq
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// ErrConfigNotUpdated is returned when the config file mounted in the pod doesn't have the expected content yet,
+// the kubelet updates ConfigMap volumes with a delay.
+var ErrConfigNotUpdated = errors.New("config file not updated yet")
+
+// ConfigPath is where the config file is mounted in the LavinMQ container.
+const ConfigPath = "/etc/lavinmq/lavinmq.ini"
+
+// LavinMQ reloads its config file on SIGHUP. The script exits with 3 unless the mounted config matches the expected
+// md5, passed as first argument, so it's never reloaded with an outdated file.
+const reloadScript = `[ "$(md5sum < ` + ConfigPath + ` | cut -d ' ' -f 1)" = "$1" ] || exit 3
+kill -HUP 1`
+
+// Exit code of reloadScript when the config file isn't updated yet.
+const configNotUpdatedExitCode = 3
+
+// ConfigReloader makes the LavinMQ node of a pod apply the settings of its config file that don't need a restart.
+type ConfigReloader interface {
+	// Reload signals LavinMQ in pod to reload its config, once the mounted file has the md5 configHash.
+	Reload(ctx context.Context, pod *corev1.Pod, configHash string) error
+}
+
+// ExecConfigReloader sends SIGHUP to LavinMQ by executing a command in the lavinmq container.
+type ExecConfigReloader struct {
+	// Config is the rest config used to connect to the API server.
+	Config *rest.Config
+}
+
+// Reload returns ErrConfigNotUpdated if the kubelet hasn't updated the mounted config file yet.
+func (r *ExecConfigReloader) Reload(ctx context.Context, pod *corev1.Pod, configHash string) error {
+	clientset, err := kubernetes.NewForConfig(r.Config)
+	if err != nil {
+		return err
+	}
+
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: "lavinmq",
+			Command:   []string{"/bin/sh", "-c", reloadScript, "reload", configHash},
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(r.Config, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+
+	stderr := &bytes.Buffer{}
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &bytes.Buffer{}, Stderr: stderr})
+	exitErr := utilexec.CodeExitError{}
+	if errors.As(err, &exitErr) && exitErr.Code == configNotUpdatedExitCode {
+		return ErrConfigNotUpdated
+	}
+	if err != nil {
+		return fmt.Errorf("reloading config of %s: %w: %s", pod.Name, err, stderr.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "lavinmq_operator"
+
+// Phases a managed LavinMQ cluster can be reported in.
+const (
+	PhaseAvailable = "Available"
+	PhaseDegraded  = "Degraded"
+)
+
+var phases = []string{PhaseAvailable, PhaseDegraded}
+
+var (
+	// ClusterPhase is 1 for the phase a LavinMQ cluster currently is in and 0 for all other phases.
+	// Use sum by (phase) to get the number of clusters in each phase.
+	ClusterPhase = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "cluster_phase",
+			Help:      "Current phase of each managed LavinMQ cluster",
+		},
+		[]string{"namespace", "name", "phase"},
+	)
+
+	// ReconcileErrors counts failures per sub-reconciler, labeled by the reconciler Name().
+	ReconcileErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "reconcile_errors_total",
+			Help:      "Number of failed reconciliations per sub-reconciler",
+		},
+		[]string{"namespace", "name", "reconciler"},
+	)
+
+	// RollingRestarts counts rolling restarts triggered by a changed config-hash annotation.
+	RollingRestarts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "rolling_restarts_total",
+			Help:      "Number of rolling restarts triggered by configuration changes",
+		},
+		[]string{"namespace", "name"},
+	)
+
+	// PVCExpansions counts storage request increases applied to data PVCs.
+	PVCExpansions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "pvc_expansions_total",
+			Help:      "Number of persistent volume claim expansions",
+		},
+		[]string{"namespace", "name", "pvc"},
+	)
+
+	// DriftCorrections counts owned resources reverted after being changed outside of the operator.
+	DriftCorrections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "drift_corrections_total",
+			Help:      "Number of owned resources reverted after being changed outside of the operator",
+		},
+		[]string{"namespace", "name", "kind"},
+	)
+
+	// LastSuccessfulReconcile holds the unix time of the last reconcile without errors.
+	// Alert on time() - lavinmq_operator_last_successful_reconcile_timestamp_seconds.
+	LastSuccessfulReconcile = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "last_successful_reconcile_timestamp_seconds",
+			Help:      "Unix timestamp of the last successful reconciliation",
+		},
+		[]string{"namespace", "name"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		ClusterPhase,
+		ReconcileErrors,
+		RollingRestarts,
+		PVCExpansions,
+		DriftCorrections,
+		LastSuccessfulReconcile,
+	)
+}
+
+// SetClusterPhase marks the given phase as the current one for the cluster.
+func SetClusterPhase(namespace, name, phase string) {
+	for _, p := range phases {
+		value := 0.0
+		if p == phase {
+			value = 1
+		}
+		ClusterPhase.WithLabelValues(namespace, name, p).Set(value)
+	}
+}
+
+// DeleteCluster removes all series belonging to a cluster, used once the LavinMQ resource is gone.
+func DeleteCluster(namespace, name string) {
+	labels := prometheus.Labels{"namespace": namespace, "name": name}
+	ClusterPhase.DeletePartialMatch(labels)
+	ReconcileErrors.DeletePartialMatch(labels)
+	RollingRestarts.DeletePartialMatch(labels)
+	PVCExpansions.DeletePartialMatch(labels)
+	DriftCorrections.DeletePartialMatch(labels)
+	LastSuccessfulReconcile.DeletePartialMatch(labels)
+}
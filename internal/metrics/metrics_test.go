@@ -0,0 +1,34 @@
+package metrics_test
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudamqp/lavinmq-operator/internal/metrics"
+)
+
+func TestSetClusterPhase(t *testing.T) {
+	metrics.SetClusterPhase("phase-ns", "phase", metrics.PhaseDegraded)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ClusterPhase.WithLabelValues("phase-ns", "phase", metrics.PhaseDegraded)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.ClusterPhase.WithLabelValues("phase-ns", "phase", metrics.PhaseAvailable)))
+
+	metrics.SetClusterPhase("phase-ns", "phase", metrics.PhaseAvailable)
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.ClusterPhase.WithLabelValues("phase-ns", "phase", metrics.PhaseDegraded)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ClusterPhase.WithLabelValues("phase-ns", "phase", metrics.PhaseAvailable)))
+}
+
+func TestDeleteCluster(t *testing.T) {
+	metrics.SetClusterPhase("delete-ns", "deleted", metrics.PhaseAvailable)
+	metrics.SetClusterPhase("delete-ns", "kept", metrics.PhaseAvailable)
+	metrics.ReconcileErrors.WithLabelValues("delete-ns", "deleted", "config").Inc()
+	metrics.LastSuccessfulReconcile.WithLabelValues("delete-ns", "deleted").SetToCurrentTime()
+
+	metrics.DeleteCluster("delete-ns", "deleted")
+
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.ReconcileErrors))
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.LastSuccessfulReconcile))
+	// Series of other clusters are left untouched
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ClusterPhase.WithLabelValues("delete-ns", "kept", metrics.PhaseAvailable)))
+}
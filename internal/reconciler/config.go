@@ -3,14 +3,12 @@ package reconciler
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"strings"
 
 	"github.com/cloudamqp/lavinmq-operator/internal/controller/utils"
 
 	ini "gopkg.in/ini.v1"
 	corev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -41,6 +39,22 @@ port = 5679
 `
 )
 
+// reloadableKeys are the keys, by section, LavinMQ applies when it reloads its config on SIGHUP. Changes to only
+// these are reloaded by the config reload reconciler instead of restarting the pods.
+var reloadableKeys = map[string][]string{
+	"main": {
+		"consumer_timeout",
+		"default_consumer_prefetch",
+		"free_disk_min",
+		"free_disk_warn",
+		"log_level",
+		"max_deleted_definitions",
+		"set_timestamp",
+		"stats_interval",
+		"stats_log_size",
+	},
+}
+
 func (reconciler *ResourceReconciler) ConfigReconciler() *ConfigReconciler {
 	return &ConfigReconciler{
 		ResourceReconciler: reconciler,
@@ -53,26 +67,26 @@ func (b *ConfigReconciler) Reconcile(ctx context.Context) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
-	err = b.GetItem(ctx, configMap)
+	live := &corev1.ConfigMap{}
+	exists, err := b.GetLiveItem(ctx, configMap, live)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			b.CreateItem(ctx, configMap)
-			return ctrl.Result{}, nil
-		}
-
 		return ctrl.Result{}, err
 	}
 
-	err = b.updateFields(ctx, configMap)
-	if err != nil {
-		return ctrl.Result{}, err
+	if exists && b.Instance.Spec.Maintenance && live.Data[ConfigFileName] != configMap.Data[ConfigFileName] {
+		b.Logger.Info("Instance in maintenance, not rolling out config changes", "name", configMap.Name)
+		configMap.Data = live.Data
 	}
 
-	err = b.Client.Update(ctx, configMap)
+	applied, err := b.ApplyIfChanged(ctx, configMap, live, exists)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	if exists && applied && live.Data[ConfigFileName] != configMap.Data[ConfigFileName] {
+		b.normalEventf(EventReasonConfigMapUpdated, "Updated %s in ConfigMap %s", ConfigFileName, configMap.Name)
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -110,9 +124,22 @@ func (b *ConfigReconciler) newObject() (*corev1.ConfigMap, error) {
 func (b *ConfigReconciler) AppendMainConfig(cfg *ini.File) {
 	mainConfig := b.Instance.Spec.Config.Main
 
+	if len(mainConfig.AuthBackends) > 0 {
+		backends := make([]string, 0, len(mainConfig.AuthBackends))
+		for _, backend := range mainConfig.AuthBackends {
+			backends = append(backends, string(backend))
+		}
+		cfg.Section("main").Key("auth_backends").SetValue(strings.Join(backends, ","))
+	}
 	if mainConfig.ConsumerTimeout != 0 {
 		cfg.Section("main").Key("consumer_timeout").SetValue(fmt.Sprintf("%d", mainConfig.ConsumerTimeout))
 	}
+	if mainConfig.ConsumerTimeoutLoopInterval != 0 {
+		cfg.Section("main").Key("consumer_timeout_loop_interval").SetValue(fmt.Sprintf("%d", mainConfig.ConsumerTimeoutLoopInterval))
+	}
+	if mainConfig.DataDirLock != nil {
+		cfg.Section("main").Key("data_dir_lock").SetValue(fmt.Sprintf("%t", *mainConfig.DataDirLock))
+	}
 	if mainConfig.DefaultConsumerPrefetch != 0 {
 		cfg.Section("main").Key("default_consumer_prefetch").SetValue(fmt.Sprintf("%d", mainConfig.DefaultConsumerPrefetch))
 	}
@@ -122,6 +149,9 @@ func (b *ConfigReconciler) AppendMainConfig(cfg *ini.File) {
 	if mainConfig.DefaultUser != "" {
 		cfg.Section("main").Key("default_user").SetValue(mainConfig.DefaultUser)
 	}
+	if mainConfig.DefaultUserOnlyLoopback != nil {
+		cfg.Section("main").Key("default_user_only_loopback").SetValue(fmt.Sprintf("%t", *mainConfig.DefaultUserOnlyLoopback))
+	}
 	if mainConfig.FreeDiskMin != 0 {
 		cfg.Section("main").Key("free_disk_min").SetValue(fmt.Sprintf("%d", mainConfig.FreeDiskMin))
 	}
@@ -134,6 +164,9 @@ func (b *ConfigReconciler) AppendMainConfig(cfg *ini.File) {
 	if mainConfig.LogLevel != "" {
 		cfg.Section("main").Key("log_level").SetValue(mainConfig.LogLevel)
 	}
+	if mainConfig.MaxConnections != 0 {
+		cfg.Section("main").Key("max_connections").SetValue(fmt.Sprintf("%d", mainConfig.MaxConnections))
+	}
 	if mainConfig.MaxDeletedDefinitions != 0 {
 		cfg.Section("main").Key("max_deleted_definitions").SetValue(fmt.Sprintf("%d", mainConfig.MaxDeletedDefinitions))
 	}
@@ -152,6 +185,12 @@ func (b *ConfigReconciler) AppendMainConfig(cfg *ini.File) {
 	if mainConfig.StatsLogSize != 0 {
 		cfg.Section("main").Key("stats_log_size").SetValue(fmt.Sprintf("%d", mainConfig.StatsLogSize))
 	}
+	if mainConfig.TcpRecvBufferSize != 0 {
+		cfg.Section("main").Key("tcp_recv_buffer_size").SetValue(fmt.Sprintf("%d", mainConfig.TcpRecvBufferSize))
+	}
+	if mainConfig.TcpSendBufferSize != 0 {
+		cfg.Section("main").Key("tcp_send_buffer_size").SetValue(fmt.Sprintf("%d", mainConfig.TcpSendBufferSize))
+	}
 	if mainConfig.TcpKeepalive != "" {
 		cfg.Section("main").Key("tcp_keepalive").SetValue(mainConfig.TcpKeepalive)
 	}
@@ -164,7 +203,13 @@ func (b *ConfigReconciler) AppendMainConfig(cfg *ini.File) {
 	if mainConfig.TlsMinVersion != "" {
 		cfg.Section("main").Key("tls_min_version").SetValue(mainConfig.TlsMinVersion)
 	}
-	if b.Instance.Spec.TlsSecret != nil {
+	if mainConfig.YieldEachDeliveredBytes != 0 {
+		cfg.Section("main").Key("yield_each_delivered_bytes").SetValue(fmt.Sprintf("%d", mainConfig.YieldEachDeliveredBytes))
+	}
+	if mainConfig.YieldEachReceivedBytes != 0 {
+		cfg.Section("main").Key("yield_each_received_bytes").SetValue(fmt.Sprintf("%d", mainConfig.YieldEachReceivedBytes))
+	}
+	if b.Instance.Spec.TLS != nil {
 		cfg.Section("main").Key("tls_cert").SetValue(fmt.Sprintf("/etc/lavinmq/tls/%s", "tls.crt"))
 		cfg.Section("main").Key("tls_key").SetValue(fmt.Sprintf("/etc/lavinmq/tls/%s", "tls.key"))
 	}
@@ -172,14 +217,20 @@ func (b *ConfigReconciler) AppendMainConfig(cfg *ini.File) {
 
 func (b *ConfigReconciler) AppendClusteringConfig(cfg *ini.File) {
 
-	if b.Instance.Spec.EtcdEndpoints != nil {
+	if b.Instance.Spec.Clustering.Enabled() {
 		cfg.Section("clustering").Key("etcd_prefix").SetValue(b.Instance.Name)
-		cfg.Section("clustering").Key("etcd_endpoints").SetValue(strings.Join(b.Instance.Spec.EtcdEndpoints, ","))
+		cfg.Section("clustering").Key("etcd_endpoints").SetValue(strings.Join(b.Instance.Spec.Clustering.EtcdEndpoints, ","))
 		cfg.Section("clustering").Key("enabled").SetValue("true")
 	}
 
-	if b.Instance.Spec.Config.Clustering.MaxUnsyncedActions != 0 {
-		cfg.Section("clustering").Key("max_unsynced_actions").SetValue(fmt.Sprintf("%d", b.Instance.Spec.Config.Clustering.MaxUnsyncedActions))
+	if b.Instance.Spec.Clustering.MaxUnsyncedActions != 0 {
+		cfg.Section("clustering").Key("max_unsynced_actions").SetValue(fmt.Sprintf("%d", b.Instance.Spec.Clustering.MaxUnsyncedActions))
+	}
+	if b.Instance.Spec.Clustering.OnLeaderElected != "" {
+		cfg.Section("clustering").Key("on_leader_elected").SetValue(b.Instance.Spec.Clustering.OnLeaderElected)
+	}
+	if b.Instance.Spec.Clustering.OnLeaderLost != "" {
+		cfg.Section("clustering").Key("on_leader_lost").SetValue(b.Instance.Spec.Clustering.OnLeaderLost)
 	}
 }
 
@@ -199,16 +250,30 @@ func (b *ConfigReconciler) AppendAmqpConfig(cfg *ini.File) {
 		cfg.Section("amqp").Key("max_message_size").SetValue(fmt.Sprintf("%d", amqpConfig.MaxMessageSize))
 	}
 
+	if amqpConfig.TcpProxyProtocol != 0 {
+		cfg.Section("amqp").Key("tcp_proxy_protocol").SetValue(fmt.Sprintf("%d", amqpConfig.TcpProxyProtocol))
+	}
+
 	if amqpConfig.TlsPort != 0 {
 		cfg.Section("amqp").Key("tls_port").SetValue(fmt.Sprintf("%d", amqpConfig.TlsPort))
 	}
 
+	if amqpConfig.UnixPath != "" {
+		cfg.Section("amqp").Key("unix_path").SetValue(amqpConfig.UnixPath)
+	}
+	if amqpConfig.UnixProxyProtocol != nil {
+		cfg.Section("amqp").Key("unix_proxy_protocol").SetValue(fmt.Sprintf("%d", *amqpConfig.UnixProxyProtocol))
+	}
+
 	cfg.Section("amqp").Key("port").SetValue(fmt.Sprintf("%d", amqpConfig.Port))
 }
 
 func (b *ConfigReconciler) AppendMqttConfig(cfg *ini.File) {
 	mqttConfig := b.Instance.Spec.Config.Mqtt
 
+	if mqttConfig.DefaultVhost != "" {
+		cfg.Section("mqtt").Key("default_vhost").SetValue(mqttConfig.DefaultVhost)
+	}
 	if mqttConfig.MaxInflightMessages != 0 {
 		cfg.Section("mqtt").Key("max_inflight_messages").SetValue(fmt.Sprintf("%d", mqttConfig.MaxInflightMessages))
 	}
@@ -217,6 +282,10 @@ func (b *ConfigReconciler) AppendMqttConfig(cfg *ini.File) {
 		cfg.Section("mqtt").Key("tls_port").SetValue(fmt.Sprintf("%d", mqttConfig.TlsPort))
 	}
 
+	if mqttConfig.UnixPath != "" {
+		cfg.Section("mqtt").Key("unix_path").SetValue(mqttConfig.UnixPath)
+	}
+
 	cfg.Section("mqtt").Key("port").SetValue(fmt.Sprintf("%d", mqttConfig.Port))
 }
 
@@ -227,23 +296,42 @@ func (b *ConfigReconciler) AppendMgmtConfig(cfg *ini.File) {
 		cfg.Section("mgmt").Key("tls_port").SetValue(fmt.Sprintf("%d", mgmtConfig.TlsPort))
 	}
 
+	if mgmtConfig.UnixPath != "" {
+		cfg.Section("mgmt").Key("unix_path").SetValue(mgmtConfig.UnixPath)
+	}
+
 	cfg.Section("mgmt").Key("port").SetValue(fmt.Sprintf("%d", mgmtConfig.Port))
 }
 
-func (b *ConfigReconciler) updateFields(_ context.Context, configMap *corev1.ConfigMap) error {
-	newConfigMap, err := b.newObject()
+// Name returns the name of the config reconciler
+func (b *ConfigReconciler) Name() string {
+	return "config"
+}
+
+// restartConfig returns config without the reloadable keys, the part of it that only applies after a restart.
+func restartConfig(config string) (string, error) {
+	cfg, err := ini.LoadSources(ini.LoadOptions{AllowBooleanKeys: true}, []byte(config))
 	if err != nil {
-		return err
+		return "", fmt.Errorf("failed to load config: %w", err)
 	}
 
-	if !reflect.DeepEqual(configMap.Data[ConfigFileName], newConfigMap.Data[ConfigFileName]) {
-		configMap.Data[ConfigFileName] = newConfigMap.Data[ConfigFileName]
+	reloadable := false
+	for section, keys := range reloadableKeys {
+		for _, key := range keys {
+			if cfg.Section(section).HasKey(key) {
+				cfg.Section(section).DeleteKey(key)
+				reloadable = true
+			}
+		}
+	}
+	if !reloadable {
+		return config, nil
 	}
 
-	return nil
-}
+	restart := strings.Builder{}
+	if _, err := cfg.WriteTo(&restart); err != nil {
+		return "", fmt.Errorf("failed to write config: %w", err)
+	}
 
-// Name returns the name of the config reconciler
-func (b *ConfigReconciler) Name() string {
-	return "config"
+	return restart.String(), nil
 }
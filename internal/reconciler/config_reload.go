@@ -0,0 +1,155 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cloudamqp/lavinmq-operator/internal/lavinmq"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Pod annotation holding the md5 of the config file the LavinMQ node last reloaded.
+const reloadedConfigHashAnnotation = "reloaded-config-hash"
+
+// How often to check whether the kubelet updated the config file mounted in the pods.
+const configReloadRequeueDelay = 10 * time.Second
+
+// ConfigReloadReconciler applies config changes that only touch reloadable keys by making the running LavinMQ
+// nodes reload their config file, changes requiring a restart roll the pods through the config hash instead.
+type ConfigReloadReconciler struct {
+	*ResourceReconciler
+}
+
+func (reconciler *ResourceReconciler) ConfigReloadReconciler() *ConfigReloadReconciler {
+	return &ConfigReloadReconciler{
+		ResourceReconciler: reconciler,
+	}
+}
+
+func (b *ConfigReloadReconciler) Reconcile(ctx context.Context) (ctrl.Result, error) {
+	if b.Reloader == nil {
+		return ctrl.Result{}, nil
+	}
+
+	if b.Instance.Spec.Maintenance {
+		b.Logger.Info("Instance in maintenance, not reloading config")
+		return ctrl.Result{}, nil
+	}
+
+	data, err := b.deployedConfig(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	hash := configHash(data)
+
+	writtenAt, err := b.configWrittenAt(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	sts := &appsv1.StatefulSet{}
+	if err := b.Client.Get(ctx, types.NamespacedName{Name: b.Instance.Name, Namespace: b.Instance.Namespace}, sts); err != nil {
+		return ctrl.Result{}, err
+	}
+	restartHash := sts.Spec.Template.Annotations[configHashAnnotation]
+
+	pods, err := b.pods(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	waiting := false
+	for _, pod := range pods {
+		if pod == nil || pod.DeletionTimestamp != nil || !podReady(pod) {
+			continue
+		}
+		// Pods with an outdated config hash are restarted with the new config.
+		if pod.Annotations[configHashAnnotation] != restartHash || pod.Annotations[reloadedConfigHashAnnotation] == hash {
+			continue
+		}
+
+		// New and recreated pods read the config file when they start, they have nothing to reload.
+		reloaded := startedAfter(pod, writtenAt)
+		if !reloaded {
+			err := b.Reloader.Reload(ctx, pod, hash)
+			if errors.Is(err, lavinmq.ErrConfigNotUpdated) {
+				b.Logger.Info("Waiting for the config file of the pod to be updated", "pod", pod.Name)
+				waiting = true
+				continue
+			}
+			if err != nil {
+				b.Logger.Error(err, "Failed to reload config", "pod", pod.Name)
+				return ctrl.Result{}, err
+			}
+		}
+
+		patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, reloadedConfigHashAnnotation, hash))
+		if err := b.Client.Patch(ctx, pod, client.RawPatch(types.MergePatchType, patch), client.FieldOwner(FieldOwner)); err != nil {
+			b.Logger.Error(err, "Failed to annotate pod", "pod", pod.Name)
+			return ctrl.Result{}, err
+		}
+		if reloaded {
+			continue
+		}
+		b.normalEventf(EventReasonConfigReloaded, "Reloaded %s in pod %s", ConfigFileName, pod.Name)
+	}
+
+	if waiting {
+		return ctrl.Result{RequeueAfter: configReloadRequeueDelay}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// configWrittenAt returns when the operator last changed the ConfigMap of the instance.
+func (b *ConfigReloadReconciler) configWrittenAt(ctx context.Context) (time.Time, error) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      b.Instance.Name,
+			Namespace: b.Instance.Namespace,
+		},
+	}
+	if err := b.GetItem(ctx, configMap); err != nil {
+		return time.Time{}, err
+	}
+
+	writtenAt := configMap.CreationTimestamp.Time
+	for _, entry := range configMap.ManagedFields {
+		if entry.Manager == FieldOwner && entry.Time != nil && entry.Time.After(writtenAt) {
+			writtenAt = entry.Time.Time
+		}
+	}
+
+	return writtenAt, nil
+}
+
+// startedAfter reports whether the lavinmq container of the pod started after t, and thereby read the config file
+// of the ConfigMap as it was at t or later.
+func startedAfter(pod *corev1.Pod, t time.Time) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == "lavinmq" && status.State.Running != nil {
+			return status.State.Running.StartedAt.After(t)
+		}
+	}
+
+	return false
+}
+
+// Name returns the name of the config reload reconciler
+func (b *ConfigReloadReconciler) Name() string {
+	return "config-reload"
+}
+
+// DependsOn returns the reconcilers that must succeed first, the config hash of the StatefulSet tells which pods
+// are restarted instead.
+func (b *ConfigReloadReconciler) DependsOn() []string {
+	return []string{b.StatefulSetReconciler().Name()}
+}
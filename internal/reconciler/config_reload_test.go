@@ -0,0 +1,174 @@
+package reconciler_test
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/cloudamqp/lavinmq-operator/internal/lavinmq"
+	"github.com/cloudamqp/lavinmq-operator/internal/reconciler"
+	testutils "github.com/cloudamqp/lavinmq-operator/internal/test_utils"
+)
+
+type recordingReloader struct {
+	notUpdated bool
+	reloaded   []string
+}
+
+func (r *recordingReloader) Reload(_ context.Context, pod *corev1.Pod, _ string) error {
+	if r.notUpdated {
+		return lavinmq.ErrConfigNotUpdated
+	}
+	r.reloaded = append(r.reloaded, pod.Name)
+	return nil
+}
+
+func TestConfigReload(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	recorder := record.NewFakeRecorder(10)
+	reloader := &recordingReloader{notUpdated: true}
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+		Recorder: recorder,
+		Reloader: reloader,
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	reconcileConfig := func() {
+		_, err := resourceReconciler.ConfigReconciler().Reconcile(t.Context())
+		assert.NoErrorf(t, err, "Failed to reconcile config")
+		_, err = resourceReconciler.StatefulSetReconciler().Reconcile(t.Context())
+		assert.NoErrorf(t, err, "Failed to reconcile statefulset")
+	}
+	restartHash := func() string {
+		sts := &appsv1.StatefulSet{}
+		err := k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+		assert.NoErrorf(t, err, "Failed to get statefulset")
+		return sts.Spec.Template.Annotations["config-hash"]
+	}
+
+	reconcileConfig()
+	initialHash := restartHash()
+	pod := createPod(t, instance, 0, instance.Spec.Image, true)
+	pod.Annotations = map[string]string{"config-hash": initialHash}
+	assert.NoError(t, k8sClient.Update(t.Context(), pod))
+
+	t.Log("Changing a reloadable key keeps the pods running")
+	instance.Spec.Config.Main.LogLevel = "debug"
+	instance.Spec.Config.Main.ConsumerTimeout = 60000
+	reconcileConfig()
+	assert.Equal(t, initialHash, restartHash())
+	assert.Contains(t, <-recorder.Events, "Normal ConfigMapUpdated")
+	assert.Empty(t, recorder.Events)
+
+	rc := resourceReconciler.ConfigReloadReconciler()
+	result, err := rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile config reload")
+	assert.NotZero(t, result.RequeueAfter, "Should wait for the config file to be updated")
+	assert.Empty(t, reloader.reloaded)
+
+	reloader.notUpdated = false
+	result, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile config reload")
+	assert.Zero(t, result.RequeueAfter)
+	assert.Equal(t, []string{instance.Name + "-0"}, reloader.reloaded)
+	assert.Contains(t, <-recorder.Events, "Normal ConfigReloaded Reloaded lavinmq.ini in pod "+instance.Name+"-0")
+
+	configMap := &corev1.ConfigMap{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, configMap)
+	assert.NoErrorf(t, err, "Failed to get configmap")
+	hash := md5.Sum([]byte(configMap.Data[reconciler.ConfigFileName]))
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, pod)
+	assert.NoErrorf(t, err, "Failed to get pod")
+	assert.Equal(t, hex.EncodeToString(hash[:]), pod.Annotations["reloaded-config-hash"])
+
+	t.Log("Reloading only once")
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile config reload")
+	assert.Len(t, reloader.reloaded, 1)
+
+	t.Log("Changing a key requiring a restart rolls the pods")
+	instance.Spec.Config.Amqp.Heartbeat = 30
+	reconcileConfig()
+	assert.NotEqual(t, initialHash, restartHash())
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile config reload")
+	assert.Len(t, reloader.reloaded, 1, "Pods with an outdated config hash are restarted, not reloaded")
+}
+
+func TestConfigReloadSkipsNewPods(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	instance.Spec.Config.Main.LogLevel = "debug"
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	recorder := record.NewFakeRecorder(10)
+	reloader := &recordingReloader{}
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+		Recorder: recorder,
+		Reloader: reloader,
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = resourceReconciler.ConfigReconciler().Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile config")
+	_, err = resourceReconciler.StatefulSetReconciler().Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile statefulset")
+	<-recorder.Events
+
+	sts := &appsv1.StatefulSet{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+
+	pod := createPod(t, instance, 0, instance.Spec.Image, true)
+	pod.Annotations = map[string]string{"config-hash": sts.Spec.Template.Annotations["config-hash"]}
+	assert.NoError(t, k8sClient.Update(t.Context(), pod))
+	// Times are stored with a precision of seconds.
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  "lavinmq",
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(time.Now().Add(time.Second))}},
+	}}
+	assert.NoError(t, k8sClient.Status().Update(t.Context(), pod))
+
+	_, err = resourceReconciler.ConfigReloadReconciler().Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile config reload")
+	assert.Empty(t, reloader.reloaded, "A pod started with the current config has nothing to reload")
+	assert.Empty(t, recorder.Events)
+
+	configMap := &corev1.ConfigMap{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, configMap)
+	assert.NoErrorf(t, err, "Failed to get configmap")
+	hash := md5.Sum([]byte(configMap.Data[reconciler.ConfigFileName]))
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, pod)
+	assert.NoErrorf(t, err, "Failed to get pod")
+	assert.Equal(t, hex.EncodeToString(hash[:]), pod.Annotations["reloaded-config-hash"])
+}
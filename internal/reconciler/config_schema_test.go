@@ -0,0 +1,135 @@
+package reconciler_test
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	ini "gopkg.in/ini.v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+	"github.com/cloudamqp/lavinmq-operator/internal/reconciler"
+	testutils "github.com/cloudamqp/lavinmq-operator/internal/test_utils"
+)
+
+// Keys of the schema the operator doesn't render, with the reason.
+var unrenderedConfigKeys = map[string]string{
+	"main.log_file":             "LavinMQ logs to stdout in the container",
+	"main.tls_keylog_file":      "only meant for debugging TLS connections",
+	"mgmt.systemd_socket_name":  "LavinMQ isn't started by systemd in the container",
+	"amqp.systemd_socket_name":  "LavinMQ isn't started by systemd in the container",
+	"mqtt.systemd_socket_name":  "LavinMQ isn't started by systemd in the container",
+	"clustering.advertised_uri": "passed on the command line with the pod name",
+}
+
+// fillConfig sets every field of the struct v points to, so all keys the operator supports get rendered.
+func fillConfig(v reflect.Value) {
+	for i := range v.NumField() {
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString("/value")
+		case reflect.Uint64:
+			field.SetUint(1)
+		case reflect.Int32:
+			field.SetInt(1)
+		case reflect.Bool:
+			field.SetBool(true)
+		case reflect.Pointer:
+			field.Set(reflect.New(field.Type().Elem()))
+			switch field.Elem().Kind() {
+			case reflect.Bool:
+				field.Elem().SetBool(true)
+			case reflect.Int32:
+				field.Elem().SetInt(1)
+			}
+		case reflect.Slice:
+			field.Set(reflect.Append(field, reflect.ValueOf("local").Convert(field.Type().Elem())))
+		}
+	}
+}
+
+// TestConfigSchema renders a config with every supported field set and compares its keys against the checked-in
+// schema of LavinMQ's config options, testdata/lavinmq_config_schema.json.
+func TestConfigSchema(t *testing.T) {
+	t.Parallel()
+	data, err := os.ReadFile("testdata/lavinmq_config_schema.json")
+	assert.NoError(t, err)
+	schema := map[string]map[string]string{}
+	assert.NoError(t, json.Unmarshal(data, &schema))
+
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	fillConfig(reflect.ValueOf(&instance.Spec.Config.Main).Elem())
+	fillConfig(reflect.ValueOf(&instance.Spec.Config.Mgmt).Elem())
+	fillConfig(reflect.ValueOf(&instance.Spec.Config.Amqp).Elem())
+	fillConfig(reflect.ValueOf(&instance.Spec.Config.Mqtt).Elem())
+	fillConfig(reflect.ValueOf(&instance.Spec.Clustering).Elem())
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+	instance.Spec.TLS = &cloudamqpcomv1beta1.TLSSpec{SecretName: "tls"}
+
+	err = testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	rc := &reconciler.ConfigReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+		},
+	}
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile config")
+
+	configMap := &corev1.ConfigMap{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, configMap)
+	assert.NoErrorf(t, err, "Failed to get configmap")
+	cfg, err := ini.Load([]byte(configMap.Data[reconciler.ConfigFileName]))
+	assert.NoErrorf(t, err, "Failed to parse config")
+
+	t.Log("Rendering only keys LavinMQ knows, with values of their type")
+	rendered := map[string]bool{}
+	for _, section := range cfg.Sections() {
+		for _, key := range section.Keys() {
+			name := section.Name() + "." + key.Name()
+			rendered[name] = true
+
+			kind, ok := schema[section.Name()][key.Name()]
+			if !assert.Truef(t, ok, "%s is not in the schema", name) {
+				continue
+			}
+			switch kind {
+			case "uint":
+				_, err = strconv.ParseUint(key.Value(), 10, 64)
+			case "int":
+				_, err = strconv.ParseInt(key.Value(), 10, 64)
+			case "bool":
+				_, err = strconv.ParseBool(key.Value())
+			default:
+				err = nil
+			}
+			assert.NoErrorf(t, err, "%s = %s is not a %s", name, key.Value(), kind)
+		}
+	}
+
+	t.Log("Rendering every key of the schema")
+	for section, keys := range schema {
+		for key := range keys {
+			name := section + "." + key
+			if _, skipped := unrenderedConfigKeys[name]; skipped {
+				assert.Falsef(t, rendered[name], "%s is rendered but listed as unrendered", name)
+				continue
+			}
+			assert.Truef(t, rendered[name], "%s is in the schema but has no field", name)
+		}
+	}
+}
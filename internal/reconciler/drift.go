@@ -0,0 +1,353 @@
+package reconciler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+	"github.com/cloudamqp/lavinmq-operator/internal/metrics"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+	"sigs.k8s.io/structured-merge-diff/v4/value"
+)
+
+// Metadata fields the operator asserts, the rest is owned by the API server.
+var comparedMetadataFields = []string{"labels", "annotations", "ownerReferences"}
+
+// fieldPath is a path into an unstructured object, made up of map keys (string) and list indexes (int).
+type fieldPath []interface{}
+
+func (p fieldPath) String() string {
+	path := strings.Builder{}
+	for _, segment := range p {
+		switch s := segment.(type) {
+		case int:
+			path.WriteString("[" + strconv.Itoa(s) + "]")
+		case string:
+			if path.Len() > 0 {
+				path.WriteString(".")
+			}
+			path.WriteString(s)
+		}
+	}
+
+	return path.String()
+}
+
+// ApplyIfChanged applies obj unless the live object already matches it, in which case obj is updated
+// with the live state and no request is made. The object is also applied when it no longer sets fields the
+// operator owns, so server-side apply removes them. Fields that differ because another field manager changed
+// them are reported as drift before being reverted. Returns whether obj was applied.
+func (reconciler *ResourceReconciler) ApplyIfChanged(ctx context.Context, obj, live client.Object, exists bool) (bool, error) {
+	if !exists {
+		return true, reconciler.ApplyItem(ctx, obj)
+	}
+
+	desired, err := reconciler.applyConfiguration(obj)
+	if err != nil {
+		return false, err
+	}
+
+	liveContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(live)
+	if err != nil {
+		return false, err
+	}
+
+	changed := diffObjects(desired.Object, liveContent)
+	removed, err := removedFields(live, desired.Object)
+	if err != nil {
+		return false, err
+	}
+	if len(changed) == 0 && len(removed) == 0 {
+		reconciler.Logger.V(1).Info("Resource up to date, skipping apply", "kind", desired.GetKind(), "name", obj.GetName())
+		return false, runtime.DefaultUnstructuredConverter.FromUnstructured(liveContent, obj)
+	}
+	if len(removed) > 0 {
+		reconciler.Logger.Info("Removing fields no longer set", "kind", desired.GetKind(), "name", obj.GetName(), "fields", removed)
+	}
+
+	drift, err := driftedFields(live, liveContent, changed)
+	if err != nil {
+		reconciler.Logger.Error(err, "Failed to attribute drift", "kind", desired.GetKind(), "name", obj.GetName())
+	}
+
+	if err := reconciler.ApplyItem(ctx, obj); err != nil {
+		return false, err
+	}
+
+	if len(drift) > 0 {
+		reconciler.recordDrift(desired.GetKind(), obj.GetName(), drift)
+	}
+
+	return true, nil
+}
+
+// recordDrift reports reverted fields, drift maps each field to the managers that changed it.
+func (reconciler *ResourceReconciler) recordDrift(kind, name string, drift map[string][]string) {
+	correction := cloudamqpcomv1beta1.DriftCorrection{
+		Resource: kind + "/" + name,
+		Time:     metav1.Now(),
+	}
+	for field, managers := range drift {
+		correction.Fields = append(correction.Fields, field)
+		for _, manager := range managers {
+			if !slices.Contains(correction.Managers, manager) {
+				correction.Managers = append(correction.Managers, manager)
+			}
+		}
+	}
+	slices.Sort(correction.Fields)
+	slices.Sort(correction.Managers)
+
+	reconciler.Logger.Info("Reverting changes made outside of the operator",
+		"resource", correction.Resource, "fields", correction.Fields, "managers", correction.Managers)
+	reconciler.warningEventf(EventReasonDriftCorrected, "Reverted %s on %s changed by %s",
+		strings.Join(correction.Fields, ", "), correction.Resource, strings.Join(correction.Managers, ", "))
+	metrics.DriftCorrections.WithLabelValues(reconciler.Instance.Namespace, reconciler.Instance.Name, kind).Inc()
+	reconciler.driftCorrections = append(reconciler.driftCorrections, correction)
+}
+
+// diffObjects returns the paths of the fields set in desired that differ from live. Fields only present
+// in live, e.g. defaults or fields set by other controllers, are ignored.
+func diffObjects(desired, live map[string]interface{}) []fieldPath {
+	changed := []fieldPath{}
+
+	for key, desiredValue := range desired {
+		switch key {
+		case "apiVersion", "kind":
+			continue
+		case "metadata":
+			desiredMeta, _ := desiredValue.(map[string]interface{})
+			liveMeta, _ := live[key].(map[string]interface{})
+			for _, field := range comparedMetadataFields {
+				changed = append(changed, diffValues(fieldPath{key, field}, desiredMeta[field], liveMeta[field])...)
+			}
+		default:
+			changed = append(changed, diffValues(fieldPath{key}, desiredValue, live[key])...)
+		}
+	}
+
+	return changed
+}
+
+func diffValues(path fieldPath, desired, live interface{}) []fieldPath {
+	if isEmpty(desired) {
+		return nil
+	}
+
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return []fieldPath{path}
+		}
+
+		changed := []fieldPath{}
+		for key, value := range d {
+			changed = append(changed, diffValues(append(slices.Clone(path), key), value, l[key])...)
+		}
+		return changed
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			return []fieldPath{path}
+		}
+
+		changed := []fieldPath{}
+		for i := range d {
+			changed = append(changed, diffValues(append(slices.Clone(path), i), d[i], l[i])...)
+		}
+		return changed
+	default:
+		if !reflect.DeepEqual(desired, live) {
+			return []fieldPath{path}
+		}
+		return nil
+	}
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		for _, value := range v {
+			if !isEmpty(value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		return len(v) == 0
+	}
+
+	return false
+}
+
+// removedFields returns the fields the operator owns on live that desired doesn't set anymore, e.g. a
+// cleared nodeSelector or a dropped label. diffObjects only walks the fields set in desired, so it misses them.
+func removedFields(live client.Object, desired map[string]interface{}) ([]string, error) {
+	removed := []string{}
+
+	for _, entry := range live.GetManagedFields() {
+		if entry.Manager != FieldOwner || entry.Operation != metav1.ManagedFieldsOperationApply ||
+			entry.Subresource != "" || entry.FieldsV1 == nil {
+			continue
+		}
+
+		owned := &fieldpath.Set{}
+		if err := owned.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+			return nil, fmt.Errorf("parsing managed fields of %s: %w", entry.Manager, err)
+		}
+
+		owned.Iterate(func(path fieldpath.Path) {
+			if !hasPath(desired, path) {
+				removed = append(removed, path.String())
+			}
+		})
+	}
+
+	return removed, nil
+}
+
+// hasPath reports whether the field at the managed fields path is set in obj.
+func hasPath(obj interface{}, path fieldpath.Path) bool {
+	current := obj
+	for _, pe := range path {
+		if pe.FieldName != nil {
+			fields, ok := current.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			if current, ok = fields[*pe.FieldName]; !ok {
+				return false
+			}
+			continue
+		}
+
+		list, ok := current.([]interface{})
+		if !ok {
+			return false
+		}
+		index := -1
+		for i, element := range list {
+			if elementMatches(pe, i, element) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return false
+		}
+		current = list[index]
+	}
+
+	return true
+}
+
+// driftedFields maps the changed fields owned by other field managers than the operator to those managers.
+// Changed fields nobody else owns are changes to the desired state and not drift.
+func driftedFields(live client.Object, liveContent map[string]interface{}, changed []fieldPath) (map[string][]string, error) {
+	drift := map[string][]string{}
+
+	for _, entry := range live.GetManagedFields() {
+		if entry.Manager == FieldOwner || entry.Subresource != "" || entry.FieldsV1 == nil {
+			continue
+		}
+
+		owned := &fieldpath.Set{}
+		if err := owned.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+			return drift, fmt.Errorf("parsing managed fields of %s: %w", entry.Manager, err)
+		}
+
+		for _, path := range changed {
+			if ownsPath(owned, path, liveContent) && !slices.Contains(drift[path.String()], entry.Manager) {
+				drift[path.String()] = append(drift[path.String()], entry.Manager)
+			}
+		}
+	}
+
+	return drift, nil
+}
+
+// ownsPath reports whether the field at path, or anything below it, is in the managed field set.
+// List indexes are resolved to the key or value of the element in the live object.
+func ownsPath(owned *fieldpath.Set, path fieldPath, live interface{}) bool {
+	current := owned
+	for i, segment := range path {
+		pe, next, ok := pathElement(current, segment, live)
+		if !ok {
+			return false
+		}
+
+		if i == len(path)-1 {
+			_, hasChildren := current.Children.Get(pe)
+			return current.Members.Has(pe) || hasChildren
+		}
+
+		if current, ok = current.Children.Get(pe); !ok {
+			return false
+		}
+		live = next
+	}
+
+	return false
+}
+
+// pathElement resolves segment, relative to the live parent value, into the path element used in set.
+func pathElement(set *fieldpath.Set, segment, parent interface{}) (fieldpath.PathElement, interface{}, bool) {
+	switch s := segment.(type) {
+	case string:
+		m, _ := parent.(map[string]interface{})
+		return fieldpath.PathElement{FieldName: &s}, m[s], true
+	case int:
+		list, _ := parent.([]interface{})
+		if s >= len(list) {
+			return fieldpath.PathElement{}, nil, false
+		}
+		element := list[s]
+
+		var match fieldpath.PathElement
+		found := false
+		matches := func(pe fieldpath.PathElement) {
+			if !found && elementMatches(pe, s, element) {
+				match, found = pe, true
+			}
+		}
+		set.Members.Iterate(matches)
+		set.Children.Iterate(matches)
+		return match, element, found
+	}
+
+	return fieldpath.PathElement{}, nil, false
+}
+
+func elementMatches(pe fieldpath.PathElement, index int, element interface{}) bool {
+	switch {
+	case pe.Key != nil:
+		fields, ok := element.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for _, key := range *pe.Key {
+			if !value.Equals(key.Value, value.NewValueInterface(fields[key.Name])) {
+				return false
+			}
+		}
+		return true
+	case pe.Value != nil:
+		return value.Equals(*pe.Value, value.NewValueInterface(element))
+	case pe.Index != nil:
+		return *pe.Index == index
+	}
+
+	return false
+}
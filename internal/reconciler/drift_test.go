@@ -0,0 +1,183 @@
+package reconciler_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/cloudamqp/lavinmq-operator/internal/reconciler"
+	testutils "github.com/cloudamqp/lavinmq-operator/internal/test_utils"
+)
+
+func TestUnchangedResourceIsNotApplied(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	countingClient := &patchCountingClient{Client: k8sClient}
+
+	rc := &reconciler.ConfigReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   countingClient,
+		},
+	}
+
+	assert.NoError(t, k8sClient.Create(t.Context(), instance))
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+	assert.Equal(t, 1, countingClient.patches)
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+	assert.Equal(t, 1, countingClient.patches, "Unchanged ConfigMap should not be applied again")
+}
+
+func TestDriftCorrection(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	recorder := record.NewFakeRecorder(10)
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+		Recorder: recorder,
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	reconcileResources := func() {
+		for _, rc := range []reconciler.Reconciler{resourceReconciler.ConfigReconciler(), resourceReconciler.StatefulSetReconciler()} {
+			_, err := rc.Reconcile(t.Context())
+			assert.NoErrorf(t, err, "Failed to reconcile %s", rc.Name())
+		}
+	}
+	reconcileResources()
+
+	sts := &appsv1.StatefulSet{}
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	err = k8sClient.Get(t.Context(), key, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+
+	sts.Spec.Replicas = ptr.To(int32(3))
+	err = k8sClient.Update(t.Context(), sts, client.FieldOwner("kubectl-edit"))
+	assert.NoErrorf(t, err, "Failed to edit statefulset")
+
+	reconcileResources()
+
+	err = k8sClient.Get(t.Context(), key, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+	assert.Equal(t, int32(1), *sts.Spec.Replicas)
+
+	assert.Equal(t, "Warning DriftCorrected Reverted spec.replicas on StatefulSet/"+instance.Name+" changed by kubectl-edit",
+		<-recorder.Events)
+}
+
+func TestDesiredChangeIsNotDrift(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	recorder := record.NewFakeRecorder(10)
+	rc := &reconciler.ConfigReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+			Recorder: recorder,
+		},
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	instance.Spec.Config.Amqp.Port = 1337
+	err = k8sClient.Update(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to update instance")
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	assert.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Normal ConfigMapUpdated Updated lavinmq.ini in ConfigMap "+instance.Name, <-recorder.Events)
+}
+
+func TestRemovedFieldsArePruned(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	instance.Spec.NodeSelector = map[string]string{"disktype": "ssd"}
+	instance.Spec.PodLabels = map[string]string{"team": "messaging"}
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	recorder := record.NewFakeRecorder(10)
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+		Recorder: recorder,
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	reconcileResources := func() {
+		for _, rc := range []reconciler.Reconciler{resourceReconciler.ConfigReconciler(), resourceReconciler.StatefulSetReconciler()} {
+			_, err := rc.Reconcile(t.Context())
+			assert.NoErrorf(t, err, "Failed to reconcile %s", rc.Name())
+		}
+	}
+	reconcileResources()
+
+	sts := &appsv1.StatefulSet{}
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	err = k8sClient.Get(t.Context(), key, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+	assert.Equal(t, "ssd", sts.Spec.Template.Spec.NodeSelector["disktype"])
+	assert.Equal(t, "messaging", sts.Spec.Template.Labels["team"])
+
+	instance.Spec.NodeSelector = nil
+	instance.Spec.PodLabels = nil
+	reconcileResources()
+
+	err = k8sClient.Get(t.Context(), key, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+	assert.Empty(t, sts.Spec.Template.Spec.NodeSelector)
+	assert.NotContains(t, sts.Spec.Template.Labels, "team")
+	assert.Empty(t, recorder.Events, "Removing fields from the spec is not drift")
+}
+
+type patchCountingClient struct {
+	client.Client
+	patches int
+}
+
+func (c *patchCountingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.patches++
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
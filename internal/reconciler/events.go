@@ -0,0 +1,46 @@
+package reconciler
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Reasons used for the events recorded on the LavinMQ resource.
+const (
+	EventReasonConfigMapUpdated          = "ConfigMapUpdated"
+	EventReasonConfigReloaded            = "ConfigReloaded"
+	EventReasonRollingRestart            = "RollingRestart"
+	EventReasonPVCExpanded               = "PVCExpanded"
+	EventReasonPVCShrinkRejected         = "PVCShrinkRejected"
+	EventReasonPVCExpansionUnsupported   = "PVCExpansionUnsupported"
+	EventReasonPVCExpansionCompleted     = "PVCExpansionCompleted"
+	EventReasonStatefulSetRecreated      = "StatefulSetRecreated"
+	EventReasonStorageMigration          = "StorageMigration"
+	EventReasonStorageMigrationCompleted = "StorageMigrationCompleted"
+	EventReasonUpgrade                   = "Upgrade"
+	EventReasonUpgradeCompleted          = "UpgradeCompleted"
+	EventReasonUpgradeRolledBack         = "UpgradeRolledBack"
+	EventReasonUpgradeBlocked            = "UpgradeBlocked"
+	EventReasonRestart                   = "Restart"
+	EventReasonRestartCompleted          = "RestartCompleted"
+	EventReasonTLSSecretChanged          = "TLSSecretChanged"
+	EventReasonReplicasChanged           = "ReplicasChanged"
+	EventReasonReplicasRejected          = "ReplicasRejected"
+	EventReasonDriftCorrected            = "DriftCorrected"
+)
+
+// Eventf records an event on the LavinMQ instance, it's a no-op when no recorder is configured.
+func (reconciler *ResourceReconciler) Eventf(eventType, reason, messageFmt string, args ...interface{}) {
+	if reconciler.Recorder == nil {
+		return
+	}
+
+	reconciler.Recorder.Eventf(reconciler.Instance, eventType, reason, messageFmt, args...)
+}
+
+func (reconciler *ResourceReconciler) normalEventf(reason, messageFmt string, args ...interface{}) {
+	reconciler.Eventf(corev1.EventTypeNormal, reason, messageFmt, args...)
+}
+
+func (reconciler *ResourceReconciler) warningEventf(reason, messageFmt string, args ...interface{}) {
+	reconciler.Eventf(corev1.EventTypeWarning, reason, messageFmt, args...)
+}
@@ -2,13 +2,15 @@ package reconciler
 
 import (
 	"context"
-	"reflect"
+	"maps"
 
 	"github.com/cloudamqp/lavinmq-operator/internal/controller/utils"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -26,29 +28,50 @@ func (reconciler *ResourceReconciler) HeadlessServiceReconciler() *HeadlessServi
 func (b *HeadlessServiceReconciler) Reconcile(ctx context.Context) (ctrl.Result, error) {
 	service := b.newObject()
 
-	err := b.GetItem(ctx, service)
+	live := &corev1.Service{}
+	exists, err := b.GetLiveItem(ctx, service, live)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			b.CreateItem(ctx, service)
-			return ctrl.Result{}, nil
-		}
-
 		return ctrl.Result{}, err
 	}
 
-	b.updateFields(ctx, service)
+	if exists && b.Instance.Spec.Maintenance {
+		// The pods keep listening on the ports of their config until maintenance is cleared.
+		service.Spec.Ports = live.Spec.Ports
+		service.Spec.Selector = live.Spec.Selector
+	} else if err := b.labelPodsForSelector(ctx, service.Spec.Selector); err != nil {
+		return ctrl.Result{}, err
+	}
 
-	err = b.Client.Update(ctx, service)
-	if err != nil {
+	if _, err := b.ApplyIfChanged(ctx, service, live, exists); err != nil {
 		return ctrl.Result{}, err
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// labelPodsForSelector labels the running pods with the selector of the Service before it's applied, while a
+// StatefulSet created by an earlier version still selects them by other labels. Otherwise the Service selects no
+// pods until the StatefulSet reconciler migrated the selector.
+func (b *HeadlessServiceReconciler) labelPodsForSelector(ctx context.Context, selector map[string]string) error {
+	sts := &appsv1.StatefulSet{}
+	err := b.Client.Get(ctx, types.NamespacedName{Name: b.Instance.Name, Namespace: b.Instance.Namespace}, sts)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if sts.Spec.Selector == nil || maps.Equal(sts.Spec.Selector.MatchLabels, selector) {
+		return nil
+	}
+
+	return b.labelPods(ctx, sts, selector)
+}
+
 func (b *HeadlessServiceReconciler) newObject() *corev1.Service {
 	servicePorts := []corev1.ServicePort{}
-	if b.Instance.Spec.EtcdEndpoints != nil {
+	if b.Instance.Spec.Clustering.Enabled() {
 		servicePorts = appendServicePorts(servicePorts, 5679, "clustering")
 	}
 
@@ -78,12 +101,13 @@ func (b *HeadlessServiceReconciler) newObject() *corev1.Service {
 
 	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      b.Instance.Name,
-			Namespace: b.Instance.Namespace,
-			Labels:    utils.LabelsForLavinMQ(b.Instance),
+			Name:        b.Instance.Name,
+			Namespace:   b.Instance.Namespace,
+			Labels:      utils.LabelsForLavinMQ(b.Instance),
+			Annotations: b.Instance.Spec.Service.Annotations,
 		},
 		Spec: corev1.ServiceSpec{
-			Selector:  b.Instance.Labels,
+			Selector:  utils.SelectorLabels(b.Instance),
 			ClusterIP: "None",
 			Ports:     servicePorts,
 		},
@@ -102,14 +126,6 @@ func appendServicePorts(servicePorts []corev1.ServicePort, port int32, name stri
 	return servicePorts
 }
 
-func (b *HeadlessServiceReconciler) updateFields(ctx context.Context, service *corev1.Service) {
-	newService := b.newObject()
-
-	if !reflect.DeepEqual(service.Spec.Ports, newService.Spec.Ports) {
-		service.Spec.Ports = newService.Spec.Ports
-	}
-}
-
 // Name returns the name of the headless service reconciler
 func (b *HeadlessServiceReconciler) Name() string {
 	return "headless-service"
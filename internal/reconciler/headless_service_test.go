@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 )
@@ -39,6 +40,12 @@ func TestDefaultHeadlessService(t *testing.T) {
 	assert.Equal(t, instance.Name, service.Name)
 	assert.Equal(t, "None", service.Spec.ClusterIP)
 	assert.Len(t, service.Spec.Ports, 3)
+	assert.Equal(t, map[string]string{
+		"app.kubernetes.io/name":       "lavinmq-operator",
+		"app.kubernetes.io/managed-by": "LavinMQController",
+		"app.kubernetes.io/instance":   instance.Name,
+		"app.kubernetes.io/component":  "broker",
+	}, service.Spec.Selector)
 }
 
 func TestCustomPorts(t *testing.T) {
@@ -107,7 +114,7 @@ func TestClusteringPort(t *testing.T) {
 
 	defer k8sClient.Delete(t.Context(), instance)
 
-	instance.Spec.EtcdEndpoints = []string{"etcd-0:2379"}
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
 	assert.NoError(t, k8sClient.Create(t.Context(), instance))
 
 	rc := &reconciler.HeadlessServiceReconciler{
@@ -170,3 +177,80 @@ func TestPortChanges(t *testing.T) {
 	})
 	assert.Equal(t, int32(1111), service.Spec.Ports[idx].Port)
 }
+
+func TestForeignServiceFieldsArePreserved(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	defer k8sClient.Delete(t.Context(), instance)
+
+	assert.NoError(t, k8sClient.Create(t.Context(), instance))
+
+	rc := &reconciler.HeadlessServiceReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+		},
+	}
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoError(t, err)
+
+	t.Log("Another controller annotates the service")
+	service := &corev1.Service{}
+	assert.NoError(t, k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, service))
+	service.Annotations = map[string]string{"mesh.example.com/inject": "true"}
+	assert.NoError(t, k8sClient.Update(t.Context(), service))
+
+	instance.Spec.Config.Amqp.Port = 1111
+	assert.NoError(t, k8sClient.Update(t.Context(), instance))
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoError(t, err)
+
+	assert.NoError(t, k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, service))
+	assert.Equal(t, "true", service.Annotations["mesh.example.com/inject"])
+	idx := slices.IndexFunc(service.Spec.Ports, func(port corev1.ServicePort) bool {
+		return port.Name == "amqp"
+	})
+	assert.Equal(t, int32(1111), service.Spec.Ports[idx].Port)
+
+	idx = slices.IndexFunc(service.ManagedFields, func(entry metav1.ManagedFieldsEntry) bool {
+		return entry.Manager == reconciler.FieldOwner
+	})
+	assert.NotEqual(t, -1, idx)
+	assert.Equal(t, metav1.ManagedFieldsOperationApply, service.ManagedFields[idx].Operation)
+}
+
+func TestServiceAnnotations(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	defer k8sClient.Delete(t.Context(), instance)
+
+	instance.Spec.Service.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+
+	rc := &reconciler.HeadlessServiceReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+		},
+	}
+
+	assert.NoError(t, k8sClient.Create(t.Context(), instance))
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	service := &corev1.Service{}
+	assert.NoError(t, k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, service))
+	assert.Equal(t, "true", service.Annotations["prometheus.io/scrape"])
+}
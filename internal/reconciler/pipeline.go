@@ -0,0 +1,118 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+	"github.com/cloudamqp/lavinmq-operator/internal/metrics"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// ErrDependencyFailed is returned for reconcilers that were skipped because a reconciler they depend on failed.
+var ErrDependencyFailed = errors.New("dependency failed")
+
+// DependentReconciler is implemented by reconcilers that can only run once the reconcilers they depend on,
+// referenced by Name(), succeeded.
+type DependentReconciler interface {
+	DependsOn() []string
+}
+
+// Failure is a reconciler that failed, or was skipped, during a pipeline run.
+type Failure struct {
+	Name string
+	Err  error
+}
+
+// PipelineResult is the outcome of running all reconcilers of an instance.
+type PipelineResult struct {
+	// Result is the merged result of all reconcilers that succeeded.
+	Result ctrl.Result
+	// Failures in the order the reconcilers ran.
+	Failures []Failure
+	// DriftCorrections made to owned resources changed outside of the operator.
+	DriftCorrections []cloudamqpcomv1beta1.DriftCorrection
+}
+
+// Err joins the errors of all failed reconcilers, nil if all succeeded.
+func (p *PipelineResult) Err() error {
+	errs := make([]error, 0, len(p.Failures))
+	for _, failure := range p.Failures {
+		errs = append(errs, fmt.Errorf("%s: %w", failure.Name, failure.Err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Message summarizes the failures, suitable for a status condition.
+func (p *PipelineResult) Message() string {
+	messages := make([]string, 0, len(p.Failures))
+	for _, failure := range p.Failures {
+		messages = append(messages, fmt.Sprintf("%s: %s", failure.Name, failure.Err))
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// RunReconcilers runs all reconcilers of the instance. A failing reconciler doesn't stop the pipeline,
+// independent resources are still reconciled while the ones depending on the failed reconciler are skipped.
+func (reconciler *ResourceReconciler) RunReconcilers(ctx context.Context) PipelineResult {
+	pipeline := PipelineResult{}
+
+	for _, r := range reconciler.Reconcilers() {
+		if failed := pipeline.failedDependency(r); failed != "" {
+			reconciler.Logger.Info("Skipping reconciler, dependency failed", "name", r.Name(), "dependency", failed)
+			pipeline.Failures = append(pipeline.Failures, Failure{
+				Name: r.Name(),
+				Err:  fmt.Errorf("skipped as %s failed: %w", failed, ErrDependencyFailed),
+			})
+			continue
+		}
+
+		result, err := r.Reconcile(ctx)
+		if err != nil {
+			reconciler.Logger.Error(err, "Failed to reconcile resource", "name", r.Name())
+			metrics.ReconcileErrors.WithLabelValues(reconciler.Instance.Namespace, reconciler.Instance.Name, r.Name()).Inc()
+			pipeline.Failures = append(pipeline.Failures, Failure{Name: r.Name(), Err: err})
+			continue
+		}
+
+		pipeline.Result = MergeResults(pipeline.Result, result)
+	}
+	pipeline.DriftCorrections = reconciler.driftCorrections
+
+	return pipeline
+}
+
+func (p *PipelineResult) failedDependency(r Reconciler) string {
+	dependent, ok := r.(DependentReconciler)
+	if !ok {
+		return ""
+	}
+
+	for _, dependency := range dependent.DependsOn() {
+		if slices.ContainsFunc(p.Failures, func(f Failure) bool { return f.Name == dependency }) {
+			return dependency
+		}
+	}
+
+	return ""
+}
+
+// MergeResults combines two results, requeueing if either asks for it and after the shortest non-zero RequeueAfter.
+func MergeResults(a, b ctrl.Result) ctrl.Result {
+	merged := ctrl.Result{
+		Requeue:      a.Requeue || b.Requeue,
+		RequeueAfter: a.RequeueAfter,
+	}
+
+	if merged.RequeueAfter == 0 || (b.RequeueAfter != 0 && b.RequeueAfter < merged.RequeueAfter) {
+		merged.RequeueAfter = b.RequeueAfter
+	}
+
+	return merged
+}
@@ -0,0 +1,22 @@
+package reconciler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/cloudamqp/lavinmq-operator/internal/reconciler"
+)
+
+func TestMergeResults(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, ctrl.Result{}, reconciler.MergeResults(ctrl.Result{}, ctrl.Result{}))
+	assert.Equal(t, ctrl.Result{Requeue: true}, reconciler.MergeResults(ctrl.Result{Requeue: true}, ctrl.Result{}))
+	assert.Equal(t, ctrl.Result{RequeueAfter: time.Minute}, reconciler.MergeResults(ctrl.Result{}, ctrl.Result{RequeueAfter: time.Minute}))
+	assert.Equal(t, ctrl.Result{RequeueAfter: time.Second},
+		reconciler.MergeResults(ctrl.Result{RequeueAfter: time.Minute}, ctrl.Result{RequeueAfter: time.Second}))
+	assert.Equal(t, ctrl.Result{Requeue: true, RequeueAfter: time.Second},
+		reconciler.MergeResults(ctrl.Result{RequeueAfter: time.Second}, ctrl.Result{Requeue: true, RequeueAfter: time.Minute}))
+}
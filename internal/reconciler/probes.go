@@ -0,0 +1,76 @@
+package reconciler
+
+import (
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// LavinMQ serves its health checks without authentication on the metrics HTTP server, which runs on every node,
+// leader and followers alike. Liveness only checks that the node responds, readiness that it serves clients or,
+// on a follower, is fully synced with the leader.
+const (
+	healthPort    = 15692
+	livenessPath  = "/health/liveness"
+	readinessPath = "/health/readiness"
+)
+
+// startupProbe gives a node restoring a large data directory time to start before liveness takes over.
+func (b *StatefulSetReconciler) startupProbe() *corev1.Probe {
+	probe := httpProbe(livenessPath)
+	probe.PeriodSeconds = 10
+	probe.FailureThreshold = 30
+	applyProbeTimings(probe, b.Instance.Spec.Probes.Startup)
+	return probe
+}
+
+func (b *StatefulSetReconciler) livenessProbe() *corev1.Probe {
+	probe := httpProbe(livenessPath)
+	probe.PeriodSeconds = 10
+	applyProbeTimings(probe, b.Instance.Spec.Probes.Liveness)
+	return probe
+}
+
+func (b *StatefulSetReconciler) readinessProbe() *corev1.Probe {
+	probe := httpProbe(readinessPath)
+	probe.InitialDelaySeconds = 5
+	probe.PeriodSeconds = 10
+	applyProbeTimings(probe, b.Instance.Spec.Probes.Readiness)
+	return probe
+}
+
+func httpProbe(path string) *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   path,
+				Port:   intstr.FromInt32(healthPort),
+				Scheme: corev1.URISchemeHTTP,
+			},
+		},
+		TimeoutSeconds:   1,
+		SuccessThreshold: 1,
+		FailureThreshold: 3,
+	}
+}
+
+// applyProbeTimings overrides the defaults of probe with the timings set in the spec.
+func applyProbeTimings(probe *corev1.Probe, timings *cloudamqpcomv1beta1.ProbeTimings) {
+	if timings == nil {
+		return
+	}
+
+	if timings.InitialDelaySeconds != nil {
+		probe.InitialDelaySeconds = *timings.InitialDelaySeconds
+	}
+	if timings.PeriodSeconds != nil {
+		probe.PeriodSeconds = *timings.PeriodSeconds
+	}
+	if timings.TimeoutSeconds != nil {
+		probe.TimeoutSeconds = *timings.TimeoutSeconds
+	}
+	if timings.FailureThreshold != nil {
+		probe.FailureThreshold = *timings.FailureThreshold
+	}
+}
@@ -3,12 +3,16 @@ package reconciler
 import (
 	"context"
 	"fmt"
+	"slices"
 
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
 	"github.com/cloudamqp/lavinmq-operator/internal/controller/utils"
+	"github.com/cloudamqp/lavinmq-operator/internal/metrics"
 
 	corev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
@@ -23,48 +27,87 @@ func (reconciler *ResourceReconciler) PVCReconciler() *PVCReconciler {
 }
 
 func (b *PVCReconciler) Reconcile(ctx context.Context) (ctrl.Result, error) {
-	pvcs := b.newObjects()
-	for _, pvc := range pvcs {
-		err := b.GetItem(ctx, &pvc)
-		if err != nil {
-			if apierrors.IsNotFound(err) {
-				err = b.CreateItem(ctx, &pvc)
-				if err != nil {
-					b.Logger.Error(err, "Failed to create PVC")
+	dataPVCs := []corev1.PersistentVolumeClaim{}
+	for _, claim := range b.volumeClaims() {
+		pvcs := b.newObjects(claim)
+		for i := range pvcs {
+			pvc := &pvcs[i]
+			live := &corev1.PersistentVolumeClaim{}
+			exists, err := b.GetLiveItem(ctx, pvc, live)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+
+			if exists && (live.DeletionTimestamp != nil || storageClassChanged(live.Spec.StorageClassName, pvc.Spec.StorageClassName)) {
+				b.Logger.V(1).Info("PVC is moved to another StorageClass by the storage migration", "name", live.Name)
+				continue
+			}
+
+			if exists {
+				if err := b.verifyResize(ctx, live, &claim.spec); err != nil {
+					return ctrl.Result{}, err
+				}
+				// The data source is immutable, keep asserting the one the PVC was restored from.
+				pvc.Spec.DataSource = live.Spec.DataSource
+			} else if claim.name == "data" {
+				if err := b.setRestoreSource(ctx, pvc, i); err != nil {
 					return ctrl.Result{}, err
 				}
-				continue
 			}
 
-			return ctrl.Result{}, err
-		}
+			applied, err := b.ApplyIfChanged(ctx, pvc, live, exists)
+			if err != nil {
+				b.Logger.Error(err, "Failed to apply PVC")
+				return ctrl.Result{}, err
+			}
 
-		err = b.updateFields(ctx, &pvc)
-		if err != nil {
-			return ctrl.Result{}, err
+			previousSize := live.Spec.Resources.Requests.Storage()
+			if exists && applied && pvc.Spec.Resources.Requests.Storage().Cmp(*previousSize) > 0 {
+				metrics.PVCExpansions.WithLabelValues(b.Instance.Namespace, b.Instance.Name, pvc.Name).Inc()
+				b.normalEventf(EventReasonPVCExpanded, "Expanded PVC %s from %s to %s",
+					pvc.Name, previousSize.String(), pvc.Spec.Resources.Requests.Storage().String())
+			}
 		}
 
-		err = b.Client.Update(ctx, &pvc)
-		if err != nil {
-			b.Logger.Error(err, "Failed to update PVC")
-			return ctrl.Result{}, err
+		if claim.name == "data" {
+			dataPVCs = pvcs
 		}
 	}
 
+	b.trackExpansion(dataPVCs)
+
 	return ctrl.Result{}, nil
 }
 
-func (b *PVCReconciler) newObjects() []corev1.PersistentVolumeClaim {
+// volumeClaim is a volume of each pod backed by a PVC, created from a volume claim template of the StatefulSet.
+type volumeClaim struct {
+	name string
+	spec corev1.PersistentVolumeClaimSpec
+}
+
+// volumeClaims returns the data volume claim followed by the claims of the additional volumes.
+func (reconciler *ResourceReconciler) volumeClaims() []volumeClaim {
+	claims := []volumeClaim{{name: "data", spec: reconciler.Instance.Spec.Persistence.DataVolumeClaimSpec}}
+	for _, volume := range reconciler.Instance.Spec.Persistence.Volumes {
+		if volume.VolumeClaimSpec != nil {
+			claims = append(claims, volumeClaim{name: volume.Name, spec: *volume.VolumeClaimSpec})
+		}
+	}
+
+	return claims
+}
+
+func (b *PVCReconciler) newObjects(claim volumeClaim) []corev1.PersistentVolumeClaim {
 	pvcs := []corev1.PersistentVolumeClaim{}
 
 	for i := 0; i < int(b.Instance.Spec.Replicas); i++ {
 		pvc := &corev1.PersistentVolumeClaim{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      fmt.Sprintf("data-%s-%d", b.Instance.Name, i),
+				Name:      fmt.Sprintf("%s-%s-%d", claim.name, b.Instance.Name, i),
 				Namespace: b.Instance.Namespace,
 				Labels:    utils.LabelsForLavinMQ(b.Instance),
 			},
-			Spec: b.Instance.Spec.DataVolumeClaimSpec,
+			Spec: *claim.spec.DeepCopy(),
 		}
 		// Forcing ReadWriteOnce for volume access mode
 		pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
@@ -74,23 +117,137 @@ func (b *PVCReconciler) newObjects() []corev1.PersistentVolumeClaim {
 	return pvcs
 }
 
-func (b *PVCReconciler) updateFields(ctx context.Context, pvc *corev1.PersistentVolumeClaim) error {
-	sizeComp := pvc.Spec.Resources.Requests.Storage().Cmp(*b.Instance.Spec.DataVolumeClaimSpec.Resources.Requests.Storage())
+// verifyResize makes sure the requested size change of an existing PVC, to the size of desired, is supported.
+func (b *PVCReconciler) verifyResize(ctx context.Context, pvc *corev1.PersistentVolumeClaim, desired *corev1.PersistentVolumeClaimSpec) error {
+	sizeComp := pvc.Spec.Resources.Requests.Storage().Cmp(*desired.Resources.Requests.Storage())
 
 	switch sizeComp {
 	case -1:
 		b.Logger.Info("Volume size changed, increasing",
 			"old", pvc.Spec.Resources.Requests.Storage(),
-			"new", b.Instance.Spec.DataVolumeClaimSpec.Resources.Requests.Storage())
-		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = b.Instance.Spec.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage]
+			"new", desired.Resources.Requests.Storage())
+		return b.verifyExpansionAllowed(ctx, pvc)
 	case 1:
 		b.Logger.Info("Volume size decreased, not supported")
+		b.warningEventf(EventReasonPVCShrinkRejected, "Rejected shrinking PVC %s from %s to %s, only increasing the size is supported",
+			pvc.Name, pvc.Spec.Resources.Requests.Storage().String(),
+			desired.Resources.Requests.Storage().String())
 		return fmt.Errorf("volume size decreased, not supported")
 	}
 
 	return nil
 }
 
+// setRestoreSource seeds a new PVC from the VolumeSnapshot of the same ordinal in the LavinMQSnapshot to restore
+// from, or from any of its VolumeSnapshots if there's none for the ordinal, e.g. the leader's when only the
+// followers were snapshotted. The nodes hold the same data so any of them works.
+func (b *PVCReconciler) setRestoreSource(ctx context.Context, pvc *corev1.PersistentVolumeClaim, ordinal int) error {
+	restore := b.Instance.Spec.Persistence.RestoreFromSnapshot
+	if restore == nil {
+		return nil
+	}
+
+	snapshot := &cloudamqpcomv1beta1.LavinMQSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      restore.Name,
+			Namespace: b.Instance.Namespace,
+		},
+	}
+	if err := b.GetItem(ctx, snapshot); err != nil {
+		b.Logger.Error(err, "Failed to fetch LavinMQSnapshot to restore from", "name", restore.Name)
+		return err
+	}
+
+	if snapshot.Status.Phase != cloudamqpcomv1beta1.SnapshotPhaseReady || len(snapshot.Status.Volumes) == 0 {
+		return fmt.Errorf("snapshot %s is not ready to restore from", restore.Name)
+	}
+
+	source := snapshot.Status.Volumes[0].VolumeSnapshot
+	sourcePVC := fmt.Sprintf("data-%s-%d", snapshot.Spec.LavinMQ, ordinal)
+	for _, volume := range snapshot.Status.Volumes {
+		if volume.PersistentVolumeClaim == sourcePVC {
+			source = volume.VolumeSnapshot
+		}
+	}
+
+	b.Logger.Info("Restoring PVC from snapshot", "name", pvc.Name, "volumeSnapshot", source)
+	pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{
+		APIGroup: ptr.To(utils.VolumeSnapshotGVK.Group),
+		Kind:     utils.VolumeSnapshotGVK.Kind,
+		Name:     source,
+	}
+
+	return nil
+}
+
+// verifyExpansionAllowed makes sure the StorageClass of the PVC allows expanding its volume.
+func (b *PVCReconciler) verifyExpansionAllowed(ctx context.Context, pvc *corev1.PersistentVolumeClaim) error {
+	className := ptr.Deref(pvc.Spec.StorageClassName, "")
+	if className == "" {
+		err := fmt.Errorf("volume expansion not supported, PVC %s has no storage class", pvc.Name)
+		b.warningEventf(EventReasonPVCExpansionUnsupported, "Rejected expanding PVC %s, it has no StorageClass", pvc.Name)
+		return err
+	}
+
+	storageClass := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: className}}
+	if err := b.GetItem(ctx, storageClass); err != nil {
+		b.Logger.Error(err, "Failed to fetch StorageClass", "name", className)
+		return err
+	}
+
+	if !ptr.Deref(storageClass.AllowVolumeExpansion, false) {
+		b.warningEventf(EventReasonPVCExpansionUnsupported, "Rejected expanding PVC %s, StorageClass %s does not allow volume expansion",
+			pvc.Name, className)
+		return fmt.Errorf("volume expansion not supported by storage class %s", className)
+	}
+
+	return nil
+}
+
+// trackExpansion reports the progress of bound PVCs being resized to the requested size in the status. The
+// capacity of a PVC is updated once both the volume and its file system have been expanded.
+func (b *PVCReconciler) trackExpansion(pvcs []corev1.PersistentVolumeClaim) {
+	target := *b.Instance.Spec.Persistence.DataVolumeClaimSpec.Resources.Requests.Storage()
+	progress := &cloudamqpcomv1beta1.VolumeExpansionStatus{TargetSize: target}
+
+	for _, pvc := range pvcs {
+		capacity := pvc.Status.Capacity.Storage()
+		if pvc.Status.Phase != corev1.ClaimBound || capacity.IsZero() {
+			continue
+		}
+
+		progress.Total++
+		if capacity.Cmp(target) >= 0 {
+			progress.Resized++
+			continue
+		}
+
+		if slices.ContainsFunc(pvc.Status.Conditions, func(c corev1.PersistentVolumeClaimCondition) bool {
+			return c.Type == corev1.PersistentVolumeClaimFileSystemResizePending && c.Status == corev1.ConditionTrue
+		}) {
+			progress.FileSystemResizePending = append(progress.FileSystemResizePending, pvc.Name)
+		}
+	}
+
+	previous := b.Instance.Status.VolumeExpansion
+	if progress.Resized == progress.Total {
+		if previous != nil {
+			b.Logger.Info("Volume expansion completed", "size", target.String())
+			b.normalEventf(EventReasonPVCExpansionCompleted, "Expanded all data volumes to %s", target.String())
+		}
+		b.Instance.Status.VolumeExpansion = nil
+		return
+	}
+
+	progress.StartTime = metav1.Now()
+	if previous != nil && previous.TargetSize.Cmp(target) == 0 {
+		progress.StartTime = previous.StartTime
+	}
+	b.Logger.Info("Volume expansion in progress", "size", target.String(), "resized", progress.Resized, "total", progress.Total,
+		"fileSystemResizePending", progress.FileSystemResizePending)
+	b.Instance.Status.VolumeExpansion = progress
+}
+
 // Name returns the name of the PVC reconciler
 func (b *PVCReconciler) Name() string {
 	return "pvc"
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"testing"
 
-	"github.com/cloudamqp/lavinmq-operator/api/v1alpha1"
+	"github.com/cloudamqp/lavinmq-operator/api/v1beta1"
 	"github.com/cloudamqp/lavinmq-operator/internal/reconciler"
 	testutils "github.com/cloudamqp/lavinmq-operator/internal/test_utils"
 
@@ -16,6 +16,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestDefaultPVCReconciler(t *testing.T) {
@@ -46,9 +47,55 @@ func TestDefaultPVCReconciler(t *testing.T) {
 
 	assert.Equal(t, fmt.Sprintf("data-%s-0", instance.Name), pvc.Name)
 	assert.Equal(t, instance.Namespace, pvc.Namespace)
-	assert.Equal(t, instance.Spec.DataVolumeClaimSpec.AccessModes, pvc.Spec.AccessModes)
+	assert.Equal(t, instance.Spec.Persistence.DataVolumeClaimSpec.AccessModes, pvc.Spec.AccessModes)
 	// No diff
-	assert.Zero(t, pvc.Spec.Resources.Requests.Storage().Cmp(*instance.Spec.DataVolumeClaimSpec.Resources.Requests.Storage()))
+	assert.Zero(t, pvc.Spec.Resources.Requests.Storage().Cmp(*instance.Spec.Persistence.DataVolumeClaimSpec.Resources.Requests.Storage()))
+}
+
+func TestAdditionalVolumeClaimPVC(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	rc := &reconciler.PVCReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+		},
+	}
+
+	instance.Spec.Persistence.Volumes = []v1beta1.VolumeSpec{
+		{
+			Name:      "segments",
+			MountPath: "/var/lib/lavinmq/segments",
+			VolumeClaimSpec: &corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &[]string{"nvme"}[0],
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("50Gi")},
+				},
+			},
+		},
+		{Name: "scratch", MountPath: "/tmp", EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	defer cleanupPvcResources(t, instance)
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile PVCs")
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	assert.NoError(t, k8sClient.Get(t.Context(), types.NamespacedName{Name: fmt.Sprintf("segments-%s-0", instance.Name), Namespace: instance.Namespace}, pvc))
+	assert.Equal(t, "nvme", *pvc.Spec.StorageClassName)
+	assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, pvc.Spec.AccessModes)
+	assert.Equal(t, "50Gi", pvc.Spec.Resources.Requests.Storage().String())
+
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: fmt.Sprintf("scratch-%s-0", instance.Name), Namespace: instance.Namespace}, pvc)
+	assert.True(t, apierrors.IsNotFound(err), "emptyDir volumes should not get a PVC")
 }
 
 func TestNoChangesToPVC(t *testing.T) {
@@ -91,7 +138,7 @@ func TestNoChangesToPVC(t *testing.T) {
 
 func TestStorageSizeIncrease(t *testing.T) {
 	t.Parallel()
-	storageClass := createStorageClass(t)
+	storageClass := createStorageClass(t, "default-sc", true)
 	defer k8sClient.Delete(t.Context(), storageClass)
 
 	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
@@ -99,7 +146,7 @@ func TestStorageSizeIncrease(t *testing.T) {
 	assert.NoErrorf(t, err, "Failed to create namespace")
 	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
 
-	instance.Spec.DataVolumeClaimSpec.StorageClassName = &[]string{storageClass.Name}[0]
+	instance.Spec.Persistence.DataVolumeClaimSpec.StorageClassName = &[]string{storageClass.Name}[0]
 	rc := &reconciler.PVCReconciler{
 		ResourceReconciler: &reconciler.ResourceReconciler{
 			Instance: instance,
@@ -122,7 +169,7 @@ func TestStorageSizeIncrease(t *testing.T) {
 	assert.NoError(t, k8sClient.Status().Update(t.Context(), pvc))
 
 	t.Log("Updating the storage size")
-	instance.Spec.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("20Gi")
+	instance.Spec.Persistence.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("20Gi")
 	assert.NoError(t, k8sClient.Update(t.Context(), instance))
 
 	t.Log("Reconciling the updated instance")
@@ -131,7 +178,7 @@ func TestStorageSizeIncrease(t *testing.T) {
 
 	pvc = &corev1.PersistentVolumeClaim{}
 	assert.NoError(t, k8sClient.Get(t.Context(), types.NamespacedName{Name: fmt.Sprintf("data-%s-0", instance.Name), Namespace: instance.Namespace}, pvc))
-	assert.Zero(t, pvc.Spec.Resources.Requests.Storage().Cmp(*instance.Spec.DataVolumeClaimSpec.Resources.Requests.Storage()))
+	assert.Zero(t, pvc.Spec.Resources.Requests.Storage().Cmp(*instance.Spec.Persistence.DataVolumeClaimSpec.Resources.Requests.Storage()))
 }
 
 func TestStorageSizeDecrease(t *testing.T) {
@@ -141,11 +188,13 @@ func TestStorageSizeDecrease(t *testing.T) {
 	assert.NoErrorf(t, err, "Failed to create namespace")
 	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
 
+	recorder := record.NewFakeRecorder(10)
 	rc := &reconciler.PVCReconciler{
 		ResourceReconciler: &reconciler.ResourceReconciler{
 			Instance: instance,
 			Scheme:   scheme.Scheme,
 			Client:   k8sClient,
+			Recorder: recorder,
 		},
 	}
 
@@ -159,7 +208,7 @@ func TestStorageSizeDecrease(t *testing.T) {
 	assert.NoError(t, err)
 
 	t.Log("Updating the storage size")
-	instance.Spec.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("5Gi")
+	instance.Spec.Persistence.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("5Gi")
 
 	err = k8sClient.Update(t.Context(), instance)
 	assert.NoError(t, err)
@@ -168,12 +217,130 @@ func TestStorageSizeDecrease(t *testing.T) {
 	_, err = rc.Reconcile(t.Context())
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "volume size decreased, not supported")
+
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Warning PVCShrinkRejected")
+}
+
+func TestStorageExpansionNotAllowed(t *testing.T) {
+	t.Parallel()
+	storageClass := createStorageClass(t, "fixed-size-sc", false)
+	defer k8sClient.Delete(t.Context(), storageClass)
+
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	instance.Spec.Persistence.DataVolumeClaimSpec.StorageClassName = &storageClass.Name
+	recorder := record.NewFakeRecorder(10)
+	rc := &reconciler.PVCReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+			Recorder: recorder,
+		},
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoError(t, err)
+
+	defer cleanupPvcResources(t, instance)
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoError(t, err)
+
+	instance.Spec.Persistence.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("20Gi")
+	assert.NoError(t, k8sClient.Update(t.Context(), instance))
+
+	_, err = rc.Reconcile(t.Context())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "volume expansion not supported by storage class fixed-size-sc")
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	assert.NoError(t, k8sClient.Get(t.Context(), types.NamespacedName{Name: fmt.Sprintf("data-%s-0", instance.Name), Namespace: instance.Namespace}, pvc))
+	assert.Equal(t, "10Gi", pvc.Spec.Resources.Requests.Storage().String())
+
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Warning PVCExpansionUnsupported")
 }
 
-func createStorageClass(t *testing.T) *storagev1.StorageClass {
+func TestStorageExpansionProgress(t *testing.T) {
+	t.Parallel()
+	storageClass := createStorageClass(t, "expandable-sc", true)
+	defer k8sClient.Delete(t.Context(), storageClass)
+
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	instance.Spec.Persistence.DataVolumeClaimSpec.StorageClassName = &storageClass.Name
+	recorder := record.NewFakeRecorder(10)
+	rc := &reconciler.PVCReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+			Recorder: recorder,
+		},
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoError(t, err)
+
+	defer cleanupPvcResources(t, instance)
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoError(t, err)
+
+	key := types.NamespacedName{Name: fmt.Sprintf("data-%s-0", instance.Name), Namespace: instance.Namespace}
+	pvc := &corev1.PersistentVolumeClaim{}
+	assert.NoError(t, k8sClient.Get(t.Context(), key, pvc))
+	pvc.Status.Phase = corev1.ClaimBound
+	pvc.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")}
+	assert.NoError(t, k8sClient.Status().Update(t.Context(), pvc))
+
+	instance.Spec.Persistence.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("20Gi")
+	assert.NoError(t, k8sClient.Update(t.Context(), instance))
+
+	t.Log("Volume expanded, waiting for the file system to be resized")
+	assert.NoError(t, k8sClient.Get(t.Context(), key, pvc))
+	pvc.Status.Conditions = []corev1.PersistentVolumeClaimCondition{
+		{Type: corev1.PersistentVolumeClaimFileSystemResizePending, Status: corev1.ConditionTrue},
+	}
+	assert.NoError(t, k8sClient.Status().Update(t.Context(), pvc))
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoError(t, err)
+
+	progress := instance.Status.VolumeExpansion
+	assert.NotNil(t, progress)
+	assert.Equal(t, "20Gi", progress.TargetSize.String())
+	assert.Equal(t, int32(1), progress.Total)
+	assert.Equal(t, int32(0), progress.Resized)
+	assert.Equal(t, []string{key.Name}, progress.FileSystemResizePending)
+
+	t.Log("File system resized")
+	assert.NoError(t, k8sClient.Get(t.Context(), key, pvc))
+	pvc.Status.Conditions = nil
+	pvc.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("20Gi")}
+	assert.NoError(t, k8sClient.Status().Update(t.Context(), pvc))
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoError(t, err)
+	assert.Nil(t, instance.Status.VolumeExpansion)
+
+	assert.Len(t, recorder.Events, 2)
+	assert.Contains(t, <-recorder.Events, "Normal PVCExpanded")
+	assert.Equal(t, "Normal PVCExpansionCompleted Expanded all data volumes to 20Gi", <-recorder.Events)
+}
+
+func createStorageClass(t *testing.T, name string, allowVolumeExpansion bool) *storagev1.StorageClass {
 	storageClass := &storagev1.StorageClass{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: "default-sc",
+			Name: name,
 		},
 		Parameters:  map[string]string{},
 		Provisioner: "k8s.io/dummy-test",
@@ -183,14 +350,14 @@ func createStorageClass(t *testing.T) *storagev1.StorageClass {
 		VolumeBindingMode: &[]storagev1.VolumeBindingMode{
 			storagev1.VolumeBindingWaitForFirstConsumer,
 		}[0],
-		AllowVolumeExpansion: &[]bool{true}[0],
+		AllowVolumeExpansion: &allowVolumeExpansion,
 	}
 
 	assert.NoError(t, k8sClient.Create(t.Context(), storageClass))
 	return storageClass
 }
 
-func cleanupPvcResources(t *testing.T, instance *v1alpha1.LavinMQ) {
+func cleanupPvcResources(t *testing.T, instance *v1beta1.LavinMQ) {
 	err := k8sClient.Delete(t.Context(), instance)
 	if err != nil {
 		t.Fatalf("Failed to delete instance: %v", err)
@@ -218,3 +385,55 @@ func cleanupPvcResources(t *testing.T, instance *v1alpha1.LavinMQ) {
 		}
 	}
 }
+
+func TestRestoreFromSnapshot(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{Replicas: &[]int32{2}[0]})
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+	instance.Spec.Persistence.RestoreFromSnapshot = &v1beta1.RestoreFromSnapshotSpec{Name: "nightly"}
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	rc := &reconciler.PVCReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+		},
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	snapshot := &v1beta1.LavinMQSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: instance.Namespace},
+		Spec:       v1beta1.LavinMQSnapshotSpec{LavinMQ: "old"},
+	}
+	assert.NoError(t, k8sClient.Create(t.Context(), snapshot))
+
+	_, err = rc.Reconcile(t.Context())
+	assert.ErrorContains(t, err, "snapshot nightly is not ready to restore from")
+
+	t.Log("Restoring once the snapshot, of the follower only, is ready")
+	snapshot.Status = v1beta1.LavinMQSnapshotStatus{
+		Phase: v1beta1.SnapshotPhaseReady,
+		Volumes: []v1beta1.VolumeSnapshotStatus{
+			{PersistentVolumeClaim: "data-old-1", VolumeSnapshot: "nightly-data-old-1", ReadyToUse: true},
+		},
+	}
+	assert.NoError(t, k8sClient.Status().Update(t.Context(), snapshot))
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile PVCs")
+	defer cleanupPvcResources(t, instance)
+
+	for i := range 2 {
+		pvc := &corev1.PersistentVolumeClaim{}
+		key := types.NamespacedName{Name: fmt.Sprintf("data-%s-%d", instance.Name, i), Namespace: instance.Namespace}
+		assert.NoError(t, k8sClient.Get(t.Context(), key, pvc))
+		assert.NotNil(t, pvc.Spec.DataSource)
+		assert.Equal(t, "VolumeSnapshot", pvc.Spec.DataSource.Kind)
+		assert.Equal(t, "nightly-data-old-1", pvc.Spec.DataSource.Name)
+	}
+}
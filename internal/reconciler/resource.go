@@ -2,21 +2,45 @@ package reconciler
 
 import (
 	"context"
+	"fmt"
 
-	cloudamqpcomv1alpha1 "github.com/cloudamqp/lavinmq-operator/api/v1alpha1"
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+	"github.com/cloudamqp/lavinmq-operator/internal/lavinmq"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/csaupgrade"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
+// FieldOwner is the field manager used when server-side applying the owned resources.
+const FieldOwner = "lavinmq-operator"
+
+// legacyFieldManager is the field manager earlier versions of the operator got assigned for their
+// create and update calls. Fields it owns are handed over to FieldOwner so that later applies can remove them.
+const legacyFieldManager = "manager"
+
 type ResourceReconciler struct {
-	Instance *cloudamqpcomv1alpha1.LavinMQ
+	Instance *cloudamqpcomv1beta1.LavinMQ
 	Scheme   *runtime.Scheme
 	Logger   logr.Logger
 	Client   client.Client
+	Recorder record.EventRecorder
+	// Leaders finds the leader of clustered instances, etcd is queried if nil.
+	Leaders lavinmq.LeaderFinder
+	// Reloader makes the pods reload their config, changes to reloadable keys aren't applied if nil.
+	Reloader lavinmq.ConfigReloader
+
+	driftCorrections []cloudamqpcomv1beta1.DriftCorrection
 }
 
 func (reconciler *ResourceReconciler) Reconcilers() []Reconciler {
@@ -25,6 +49,10 @@ func (reconciler *ResourceReconciler) Reconcilers() []Reconciler {
 		reconciler.HeadlessServiceReconciler(),
 		reconciler.PVCReconciler(),
 		reconciler.StatefulSetReconciler(),
+		reconciler.ConfigReloadReconciler(),
+		reconciler.StorageMigrationReconciler(),
+		reconciler.UpgradeReconciler(),
+		reconciler.RestartReconciler(),
 	}
 }
 
@@ -43,19 +71,150 @@ func (reconciler *ResourceReconciler) GetItem(ctx context.Context, obj client.Ob
 	return nil
 }
 
-func (reconciler *ResourceReconciler) CreateItem(ctx context.Context, obj client.Object) error {
+// leaderFinder returns the configured LeaderFinder or one querying etcd.
+func (reconciler *ResourceReconciler) leaderFinder() lavinmq.LeaderFinder {
+	if reconciler.Leaders == nil {
+		return &lavinmq.EtcdLeaderFinder{}
+	}
+
+	return reconciler.Leaders
+}
+
+// nextPodLeaderLast picks the next of the pending pods to replace, the leader is only picked once all followers
+// are done so it hands over the leadership to a node that already is.
+func (reconciler *ResourceReconciler) nextPodLeaderLast(ctx context.Context, pending []string) (string, error) {
+	if !reconciler.Instance.Spec.Clustering.Enabled() || reconciler.Instance.Spec.Replicas == 1 {
+		return pending[0], nil
+	}
+
+	leader, err := reconciler.leaderFinder().Leader(ctx, reconciler.Instance)
+	if err != nil {
+		reconciler.Logger.Error(err, "Failed to find leader")
+		return "", err
+	}
+
+	for _, pod := range pending {
+		if pod != leader {
+			return pod, nil
+		}
+	}
+
+	reconciler.Logger.Info("All followers done, continuing with the leader", "leader", leader)
+	return leader, nil
+}
+
+// pods returns the pods of the instance by ordinal, nil for the ones that don't exist.
+func (reconciler *ResourceReconciler) pods(ctx context.Context) ([]*corev1.Pod, error) {
+	pods := []*corev1.Pod{}
+	for i := range int(reconciler.Instance.Spec.Replicas) {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%d", reconciler.Instance.Name, i),
+				Namespace: reconciler.Instance.Namespace,
+			},
+		}
+		if err := reconciler.GetItem(ctx, pod); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			pod = nil
+		}
+		pods = append(pods, pod)
+	}
+
+	return pods, nil
+}
+
+// deletePod deletes the pod name, the StatefulSet recreates it from the current pod template.
+func (reconciler *ResourceReconciler) deletePod(ctx context.Context, name string) error {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: reconciler.Instance.Namespace,
+		},
+	}
+	if err := reconciler.Client.Delete(ctx, pod); err != nil && !apierrors.IsNotFound(err) {
+		reconciler.Logger.Error(err, "Failed to delete pod", "name", name)
+		return err
+	}
+
+	return nil
+}
+
+// GetLiveItem fetches the current state of obj into live, returning false if it doesn't exist yet.
+func (reconciler *ResourceReconciler) GetLiveItem(ctx context.Context, obj, live client.Object) (bool, error) {
+	err := reconciler.Client.Get(ctx, client.ObjectKeyFromObject(obj), live)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
 
-	reconciler.Logger.Info("Creating item", "name", obj.GetName(), "namespace", obj.GetNamespace())
-	// Set owner reference
+		return false, err
+	}
+
+	if err := reconciler.upgradeManagedFields(ctx, live); err != nil {
+		reconciler.Logger.Error(err, "Failed to migrate managed fields", "name", live.GetName())
+		return true, err
+	}
+
+	return true, nil
+}
+
+// ApplyItem server-side applies obj with the operator as field manager. Only the fields set on obj are
+// asserted, fields added by other controllers or admission plugins are left untouched. On success obj
+// is updated with the state returned by the API server.
+func (reconciler *ResourceReconciler) ApplyItem(ctx context.Context, obj client.Object) error {
+	applyConfig, err := reconciler.applyConfiguration(obj)
+	if err != nil {
+		return err
+	}
+
+	err = reconciler.Client.Patch(ctx, applyConfig, client.Apply, client.FieldOwner(FieldOwner), client.ForceOwnership)
+	if err != nil {
+		reconciler.Logger.Error(err, "Failed to apply resource", "kind", applyConfig.GetKind(), "name", obj.GetName())
+		return err
+	}
+
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(applyConfig.Object, obj)
+}
+
+// applyConfiguration turns obj into the object sent with a server-side apply, containing only the
+// fields the operator asserts.
+func (reconciler *ResourceReconciler) applyConfiguration(obj client.Object) (*unstructured.Unstructured, error) {
 	if err := ctrl.SetControllerReference(reconciler.Instance, obj, reconciler.Scheme); err != nil {
 		reconciler.Logger.Error(err, "Failed to set controller reference", "name", obj.GetName())
-		return err
+		return nil, err
 	}
 
-	if err := reconciler.Client.Create(ctx, obj); err != nil {
-		reconciler.Logger.Error(err, "Failed to create resource", "name", obj.GetName())
+	gvk, err := apiutil.GVKForObject(obj, reconciler.Scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	applyConfig := &unstructured.Unstructured{Object: content}
+	applyConfig.SetGroupVersionKind(gvk)
+	// Status is not ours to assert and an unset creation timestamp serializes as null.
+	unstructured.RemoveNestedField(applyConfig.Object, "status")
+	unstructured.RemoveNestedField(applyConfig.Object, "metadata", "creationTimestamp")
+	applyConfig.SetManagedFields(nil)
+	applyConfig.SetResourceVersion("")
+
+	return applyConfig, nil
+}
+
+// upgradeManagedFields moves ownership of fields set through create/update calls by the legacy field
+// manager over to FieldOwner.
+func (reconciler *ResourceReconciler) upgradeManagedFields(ctx context.Context, live client.Object) error {
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(live, sets.New(legacyFieldManager), FieldOwner)
+	if err != nil || patch == nil {
 		return err
 	}
 
-	return nil
+	reconciler.Logger.Info("Migrating managed fields to server-side apply", "name", live.GetName())
+	return reconciler.Client.Patch(ctx, live, client.RawPatch(types.JSONPatchType, patch))
 }
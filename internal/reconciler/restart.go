@@ -0,0 +1,131 @@
+package reconciler
+
+import (
+	"context"
+	"time"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// How often to check on the pods while they're restarted.
+const restartRequeueDelay = 10 * time.Second
+
+// RestartReconciler restarts the pods when the restartedAt annotation of the LavinMQ changes. The pods of a cluster
+// are restarted one at a time, followers first and the leader last, each once all pods are ready, while the
+// StatefulSet uses the OnDelete update strategy. Single nodes are restarted by the StatefulSet itself.
+type RestartReconciler struct {
+	*ResourceReconciler
+}
+
+func (reconciler *ResourceReconciler) RestartReconciler() *RestartReconciler {
+	return &RestartReconciler{
+		ResourceReconciler: reconciler,
+	}
+}
+
+func (b *RestartReconciler) Reconcile(ctx context.Context) (ctrl.Result, error) {
+	requested := b.restartRequested()
+	if requested == "" {
+		return ctrl.Result{}, nil
+	}
+
+	if b.Instance.Spec.Maintenance {
+		b.Logger.Info("Instance in maintenance, not restarting", "restartedAt", requested)
+		return ctrl.Result{}, nil
+	}
+	b.Instance.Status.RestartRequestedAt = requested
+
+	if b.upgrading() {
+		// Pods replaced by the upgrade are restarted as well.
+		b.Logger.Info("Waiting for the image upgrade to complete before restarting")
+		return ctrl.Result{RequeueAfter: restartRequeueDelay}, nil
+	}
+
+	pods, err := b.pods(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	pending := []string{}
+	for _, pod := range pods {
+		if pod == nil || pod.DeletionTimestamp != nil || !podReady(pod) {
+			b.Logger.Info("Waiting for pods to be ready before continuing the restart", "restartedAt", requested)
+			return ctrl.Result{RequeueAfter: restartRequeueDelay}, nil
+		}
+		if pod.Annotations[restartedAtAnnotation] != requested {
+			pending = append(pending, pod.Name)
+		}
+	}
+
+	if len(pending) == 0 {
+		b.Logger.Info("Restart completed", "restartedAt", requested)
+		b.normalEventf(EventReasonRestartCompleted, "Restarted all pods, requested at %s", requested)
+		b.Instance.Status.RestartedAt = requested
+		b.Instance.Status.RestartRequestedAt = ""
+		b.Instance.Status.RestartCompletedAt = ptr.To(metav1.Now())
+		return ctrl.Result{}, nil
+	}
+
+	if !b.orderedRestart() {
+		// The StatefulSet rolls the pods itself.
+		return ctrl.Result{RequeueAfter: restartRequeueDelay}, nil
+	}
+
+	pod, err := b.nextPodLeaderLast(ctx, pending)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := b.deletePod(ctx, pod); err != nil {
+		return ctrl.Result{}, err
+	}
+	b.normalEventf(EventReasonRestart, "Restarting pod %s, requested at %s", pod, requested)
+
+	return ctrl.Result{RequeueAfter: restartRequeueDelay}, nil
+}
+
+// restartedAt returns the restartedAt annotation the pods should carry. The restart in progress, or else the last
+// completed one, is kept when the annotation is removed, so that doesn't restart the pods again.
+func (reconciler *ResourceReconciler) restartedAt() string {
+	status := &reconciler.Instance.Status
+	if requested := reconciler.Instance.Annotations[cloudamqpcomv1beta1.RestartedAtAnnotation]; requested != "" {
+		return requested
+	}
+	if status.RestartRequestedAt != "" {
+		return status.RestartRequestedAt
+	}
+
+	return status.RestartedAt
+}
+
+// restartRequested returns the restartedAt annotation if the pods haven't all been restarted for it yet.
+func (reconciler *ResourceReconciler) restartRequested() string {
+	requested := reconciler.restartedAt()
+	if requested == reconciler.Instance.Status.RestartedAt {
+		return ""
+	}
+
+	return requested
+}
+
+// orderedRestart reports whether the pods are restarted one at a time by the operator, leader last, instead of
+// by the StatefulSet.
+func (reconciler *ResourceReconciler) orderedRestart() bool {
+	return reconciler.restartRequested() != "" && reconciler.Instance.Spec.Clustering.Enabled() &&
+		reconciler.Instance.Spec.Replicas > 1
+}
+
+// Name returns the name of the restart reconciler
+func (b *RestartReconciler) Name() string {
+	return "restart"
+}
+
+// DependsOn returns the reconcilers that must succeed first, the StatefulSet must carry the restartedAt
+// annotation before pods are deleted.
+func (b *RestartReconciler) DependsOn() []string {
+	return []string{b.StatefulSetReconciler().Name()}
+}
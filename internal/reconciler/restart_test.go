@@ -0,0 +1,192 @@
+package reconciler_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+	"github.com/cloudamqp/lavinmq-operator/internal/reconciler"
+	testutils "github.com/cloudamqp/lavinmq-operator/internal/test_utils"
+)
+
+func TestRestartLeaderLast(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{Replicas: ptr.To(int32(3))})
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	recorder := record.NewFakeRecorder(10)
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+		Recorder: recorder,
+		Leaders:  staticLeader(instance.Name + "-0"),
+	}
+
+	instance.Status.CurrentImage = instance.Spec.Image
+	instance.Annotations = map[string]string{cloudamqpcomv1beta1.RestartedAtAnnotation: "2025-06-01T12:00:00Z"}
+	for i := range 3 {
+		createPod(t, instance, i, instance.Spec.Image, true)
+	}
+
+	rc := resourceReconciler.RestartReconciler()
+	result, err := rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile restart")
+	assert.NotZero(t, result.RequeueAfter)
+
+	t.Log("Restarting a follower first")
+	pod := &corev1.Pod{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name + "-1", Namespace: instance.Namespace}, pod)
+	assert.True(t, err != nil || pod.DeletionTimestamp != nil, "Pod should be deleted")
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name + "-0", Namespace: instance.Namespace}, pod)
+	assert.NoError(t, err)
+	assert.Nil(t, pod.DeletionTimestamp)
+	assert.Contains(t, <-recorder.Events, "Normal Restart Restarting pod "+instance.Name+"-1, requested at 2025-06-01T12:00:00Z")
+
+	t.Log("Completing once all pods are restarted")
+	for i := range 3 {
+		deletePod(t, instance, i)
+		pod := createPod(t, instance, i, instance.Spec.Image, true)
+		pod.Annotations = map[string]string{"restartedAt": "2025-06-01T12:00:00Z"}
+		assert.NoError(t, k8sClient.Update(t.Context(), pod))
+	}
+
+	result, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile restart")
+	assert.Zero(t, result.RequeueAfter)
+	assert.Equal(t, "2025-06-01T12:00:00Z", instance.Status.RestartedAt)
+	assert.NotNil(t, instance.Status.RestartCompletedAt)
+	assert.Contains(t, <-recorder.Events, "Normal RestartCompleted Restarted all pods, requested at 2025-06-01T12:00:00Z")
+}
+
+func TestRestartedAtAnnotation(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{Replicas: ptr.To(int32(3))})
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	recorder := record.NewFakeRecorder(10)
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+		Recorder: recorder,
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+	instance.Status.CurrentImage = instance.Spec.Image
+
+	rc := resourceReconciler.StatefulSetReconciler()
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile statefulset")
+
+	t.Log("Propagating the annotation to the pod template")
+	instance.Annotations = map[string]string{cloudamqpcomv1beta1.RestartedAtAnnotation: "2025-06-01T12:00:00Z"}
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile statefulset")
+
+	sts := &appsv1.StatefulSet{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+	assert.Equal(t, "2025-06-01T12:00:00Z", sts.Spec.Template.Annotations["restartedAt"])
+	assert.NotEmpty(t, sts.Spec.Template.Annotations["config-hash"])
+	assert.Equal(t, appsv1.OnDeleteStatefulSetStrategyType, sts.Spec.UpdateStrategy.Type)
+	assert.Contains(t, <-recorder.Events, fmt.Sprintf("Normal RollingRestart Restart requested at 2025-06-01T12:00:00Z, rolling restart of StatefulSet %s triggered", instance.Name))
+
+	t.Log("Keeping the pods once the restart completed and the annotation is removed")
+	instance.Status.RestartedAt = "2025-06-01T12:00:00Z"
+	instance.Annotations = nil
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile statefulset")
+
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+	assert.Equal(t, "2025-06-01T12:00:00Z", sts.Spec.Template.Annotations["restartedAt"])
+	assert.Equal(t, appsv1.RollingUpdateStatefulSetStrategyType, sts.Spec.UpdateStrategy.Type)
+}
+
+func TestRestartAnnotationRemovedMidRestart(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{Replicas: ptr.To(int32(3))})
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	recorder := record.NewFakeRecorder(10)
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+		Recorder: recorder,
+		Leaders:  staticLeader(instance.Name + "-0"),
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+	instance.Status.CurrentImage = instance.Spec.Image
+	for i := range 3 {
+		createPod(t, instance, i, instance.Spec.Image, true)
+	}
+
+	reconcileRestart := func() {
+		for _, rc := range []reconciler.Reconciler{resourceReconciler.StatefulSetReconciler(), resourceReconciler.RestartReconciler()} {
+			_, err := rc.Reconcile(t.Context())
+			assert.NoErrorf(t, err, "Failed to reconcile %s", rc.Name())
+		}
+	}
+	restartedAt := func() string {
+		sts := &appsv1.StatefulSet{}
+		err := k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+		assert.NoErrorf(t, err, "Failed to get statefulset")
+		return sts.Spec.Template.Annotations["restartedAt"]
+	}
+
+	instance.Annotations = map[string]string{cloudamqpcomv1beta1.RestartedAtAnnotation: "2025-06-01T12:00:00Z"}
+	reconcileRestart()
+	assert.Equal(t, "2025-06-01T12:00:00Z", instance.Status.RestartRequestedAt)
+	deletePod(t, instance, 1)
+	pod := createPod(t, instance, 1, instance.Spec.Image, true)
+	pod.Annotations = map[string]string{"restartedAt": "2025-06-01T12:00:00Z"}
+	assert.NoError(t, k8sClient.Update(t.Context(), pod))
+
+	t.Log("Completing the restart in progress when the annotation is removed")
+	instance.Annotations = nil
+	reconcileRestart()
+	assert.Equal(t, "2025-06-01T12:00:00Z", restartedAt(), "The restarted pods must not be rolled again")
+	assert.Equal(t, "2025-06-01T12:00:00Z", instance.Status.RestartRequestedAt)
+
+	for _, i := range []int{0, 2} {
+		deletePod(t, instance, i)
+		pod := createPod(t, instance, i, instance.Spec.Image, true)
+		pod.Annotations = map[string]string{"restartedAt": "2025-06-01T12:00:00Z"}
+		assert.NoError(t, k8sClient.Update(t.Context(), pod))
+	}
+	reconcileRestart()
+	assert.Equal(t, "2025-06-01T12:00:00Z", instance.Status.RestartedAt)
+	assert.Empty(t, instance.Status.RestartRequestedAt)
+	assert.Equal(t, "2025-06-01T12:00:00Z", restartedAt())
+}
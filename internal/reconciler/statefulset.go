@@ -5,20 +5,71 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
-	"reflect"
+	"maps"
 	"slices"
+	"strings"
+	"time"
 
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
 	"github.com/cloudamqp/lavinmq-operator/internal/controller/utils"
-	resource_utils "github.com/cloudamqp/lavinmq-operator/internal/reconciler/utils"
+	"github.com/cloudamqp/lavinmq-operator/internal/lavinmq"
+	"github.com/cloudamqp/lavinmq-operator/internal/metrics"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/util/retry"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// Pod template annotation holding the md5 of the rendered config, changing it rolls the pods.
+const configHashAnnotation = "config-hash"
+
+// Pod template annotation holding the restartedAt annotation of the LavinMQ, changing it restarts the pods.
+const restartedAtAnnotation = "restartedAt"
+
+// preStopScript stops the app before the pod is stopped. The terminating pod is already removed from the Service,
+// so stopping the app closes the client connections gracefully and they reconnect to the next leader.
+const preStopScript = `lavinmqctl stop_app || true`
+
+// leaderHandoverScript hands over the leadership of a clustered instance before the pod is stopped. LavinMQ
+// releases its leader lease when the app stops, the script then polls the leader key in etcd, the way the
+// EtcdLeaderFinder does, until another pod holds it or the deadline passes. Without curl in the image it only
+// stops the app. The script is formatted with the deadline in seconds, the etcd endpoints and the range request.
+const leaderHandoverScript = `lavinmqctl status | grep -q follower && exit 0
+lavinmqctl stop_app || exit 0
+command -v curl >/dev/null || exit 0
+deadline=$(($(date +%%s) + %d))
+while [ "$(date +%%s)" -lt "$deadline" ]; do
+  for endpoint in %s; do
+    leader=$(curl -sf -m 2 -X POST "$endpoint/v3/kv/range" -d '%s' |
+      sed -n 's/.*"value":"\([^"]*\)".*/\1/p' | base64 -d 2>/dev/null)
+    case "$leader" in
+      "") continue ;;
+      *"://$POD_NAME."* | *"://$POD_NAME:"*) break ;;
+      *) exit 0 ;;
+    esac
+  done
+  sleep 1
+done
+exit 0`
+
+// Part of the grace period kept for LavinMQ to shut down after the leader handover timed out.
+const shutdownGracePeriodSeconds = 5
+
+// ConditionReplicasRejected is true while the StatefulSet is kept at fewer replicas than requested, as replication
+// requires etcd.
+const ConditionReplicasRejected = "ReplicasRejected"
+
+// How long to wait for the garbage collector to remove a StatefulSet deleted with orphaned pods.
+const recreateRequeueDelay = 5 * time.Second
+
 type StatefulSetReconciler struct {
 	*ResourceReconciler
 }
@@ -36,35 +87,108 @@ func (b *StatefulSetReconciler) Reconcile(ctx context.Context) (ctrl.Result, err
 		return ctrl.Result{}, err
 	}
 
-	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		if err := b.GetItem(ctx, statefulset); err != nil {
-			if apierrors.IsNotFound(err) {
-				b.CreateItem(ctx, statefulset)
-				return nil
-			}
+	live := &appsv1.StatefulSet{}
+	exists, err := b.GetLiveItem(ctx, statefulset, live)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
-			return err
+	// The API server rejects this, also for updates through the scale subresource, but instances created
+	// before the validation rules existed could still have it set.
+	replicas := b.Instance.Spec.Replicas
+	if replicas > 1 && !b.Instance.Spec.Clustering.Enabled() {
+		replicas = 1
+		if exists {
+			replicas = min(ptr.Deref(live.Spec.Replicas, 1), b.Instance.Spec.Replicas)
 		}
+		statefulset.Spec.Replicas = &replicas
+	}
+	b.reportRejectedReplicas(replicas)
 
-		if err := b.updateFields(ctx, statefulset); err != nil {
-			b.Logger.Error(err, "Failed calculating new statefulset")
-			return err
+	if exists && live.DeletionTimestamp != nil {
+		b.Logger.Info("Waiting for StatefulSet to be deleted before recreating it", "name", live.Name)
+		return ctrl.Result{RequeueAfter: recreateRequeueDelay}, nil
+	}
+
+	if exists && b.Instance.Spec.Maintenance {
+		// Any change to the template or the selector would roll or recreate the pods.
+		b.Logger.Info("Instance in maintenance, keeping the pods as they are", "name", live.Name)
+		statefulset.Spec.Replicas = live.Spec.Replicas
+		statefulset.Spec.Selector = live.Spec.Selector
+		statefulset.Spec.Template = live.Spec.Template
+		statefulset.Spec.UpdateStrategy = live.Spec.UpdateStrategy
+		statefulset.Spec.VolumeClaimTemplates = live.Spec.VolumeClaimTemplates
+	} else if exists {
+		outdated, err := b.volumeClaimTemplateOutdated(ctx, live)
+		if err != nil {
+			return ctrl.Result{}, err
 		}
 
-		if err := b.Client.Update(ctx, statefulset); err != nil {
-			// Conflict errors are expected during retries and do not indicate a critical issue.
-			// Logging them would create unnecessary noise in the logs.
-			if !apierrors.IsConflict(err) {
-				b.Logger.Error(err, "Failed updating new statefulset")
+		if outdated {
+			if err := b.deleteOrphaningPods(ctx, live); err != nil {
+				return ctrl.Result{}, err
 			}
+			b.normalEventf(EventReasonStatefulSetRecreated, "Recreating StatefulSet %s to update its volume claim template, pods are kept running",
+				live.Name)
+			return ctrl.Result{RequeueAfter: recreateRequeueDelay}, nil
+		}
 
-			return err
+		if !maps.Equal(live.Spec.Selector.MatchLabels, statefulset.Spec.Selector.MatchLabels) {
+			if err := b.migrateSelector(ctx, live, statefulset.Spec.Selector.MatchLabels); err != nil {
+				return ctrl.Result{}, err
+			}
+			b.normalEventf(EventReasonStatefulSetRecreated, "Recreating StatefulSet %s to update its selector, pods are kept running and rolled once",
+				live.Name)
+			return ctrl.Result{RequeueAfter: recreateRequeueDelay}, nil
 		}
 
-		return nil
-	})
+		// VolumeClaimTemplates are immutable, the PVCs themselves are resized by the PVC reconciler.
+		statefulset.Spec.VolumeClaimTemplates = live.Spec.VolumeClaimTemplates
 
-	return ctrl.Result{}, err
+		if statefulset.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType && live.Spec.UpdateStrategy.RollingUpdate != nil {
+			if err := b.switchToOnDelete(ctx, live); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	applied, err := b.ApplyIfChanged(ctx, statefulset, live, exists)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Reported through the scale subresource, for autoscalers and kubectl scale.
+	b.Instance.Status.Replicas = live.Status.Replicas
+	b.Instance.Status.Selector = labels.SelectorFromSet(statefulset.Spec.Selector.MatchLabels).String()
+
+	if exists && applied {
+		b.recordChanges(live, statefulset)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// reportRejectedReplicas sets the ReplicasRejected condition while the StatefulSet is kept below spec.replicas,
+// with a warning when the scaling gets rejected.
+func (b *StatefulSetReconciler) reportRejectedReplicas(replicas int32) {
+	status := &b.Instance.Status
+	if replicas >= b.Instance.Spec.Replicas {
+		meta.RemoveStatusCondition(&status.Conditions, ConditionReplicasRejected)
+		return
+	}
+
+	message := fmt.Sprintf("Rejected scaling to %d replicas, a provided etcd cluster is required for replication",
+		b.Instance.Spec.Replicas)
+	changed := meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               ConditionReplicasRejected,
+		Status:             metav1.ConditionTrue,
+		Reason:             EventReasonReplicasRejected,
+		Message:            message,
+		ObservedGeneration: b.Instance.Generation,
+	})
+	if changed {
+		b.warningEventf(EventReasonReplicasRejected, "%s", message)
+	}
 }
 
 func (b *StatefulSetReconciler) newObject(ctx context.Context) (*appsv1.StatefulSet, error) {
@@ -79,10 +203,14 @@ func (b *StatefulSetReconciler) newObject(ctx context.Context) (*appsv1.Stateful
 	}
 
 	b.appendSpec(sts)
+	b.appendVolumes(sts)
 	b.appendTlsConfig(sts)
 	if err := b.setConfigHashAnnotation(ctx, sts); err != nil {
 		return nil, err
 	}
+	if restartedAt := b.restartedAt(); restartedAt != "" {
+		sts.Spec.Template.Annotations[restartedAtAnnotation] = restartedAt
+	}
 
 	return sts, nil
 }
@@ -93,20 +221,24 @@ func (b *StatefulSetReconciler) appendSpec(sts *appsv1.StatefulSet) *appsv1.Stat
 	sts.Spec = appsv1.StatefulSetSpec{
 		Replicas: &b.Instance.Spec.Replicas,
 		Selector: &metav1.LabelSelector{
-			MatchLabels: sts.Labels,
+			MatchLabels: utils.SelectorLabels(b.Instance),
 		},
-		ServiceName: b.Instance.Name,
+		ServiceName:    b.Instance.Name,
+		UpdateStrategy: b.updateStrategy(),
 		Template: corev1.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels:      sts.Labels,
-				Annotations: make(map[string]string),
+				Labels:      b.podLabels(),
+				Annotations: b.podAnnotations(),
 			},
 			Spec: corev1.PodSpec{
 				NodeSelector: b.Instance.Spec.NodeSelector,
+				Affinity:     b.Instance.Spec.Affinity,
+				TerminationGracePeriodSeconds: ptr.To(ptr.Deref(b.Instance.Spec.TerminationGracePeriodSeconds,
+					cloudamqpcomv1beta1.DefaultTerminationGracePeriodSeconds)),
 				Containers: []corev1.Container{
 					{
 						Name:      "lavinmq",
-						Image:     b.Instance.Spec.Image,
+						Image:     b.deployedImage(),
 						Resources: b.Instance.Spec.Resources,
 						Command:   []string{"/usr/bin/lavinmq"},
 						Args:      b.cliArgs(),
@@ -140,33 +272,14 @@ func (b *StatefulSetReconciler) appendSpec(sts *appsv1.StatefulSet) *appsv1.Stat
 								},
 							},
 						},
-						// Startup probe will be used for startup of the container. Once the startup probe succeeds,
-						// the liveness and readiness probes will be used.
-						StartupProbe: &corev1.Probe{
-							ProbeHandler: corev1.ProbeHandler{
-								Exec: &corev1.ExecAction{
-									Command: []string{"/bin/sh", "-c", "/usr/bin/lavinmqctl status || /usr/bin/lavinmqctl status | grep -q follower"},
-								},
-							},
-							FailureThreshold: 30,
-							PeriodSeconds:    10,
-						},
-						LivenessProbe: &corev1.Probe{
-							ProbeHandler: corev1.ProbeHandler{
-								Exec: &corev1.ExecAction{
-									Command: []string{"/bin/sh", "-c", "/usr/bin/lavinmqctl status || /usr/bin/lavinmqctl status | grep -q follower"},
-								},
-							},
-							PeriodSeconds: 10,
-						},
-						ReadinessProbe: &corev1.Probe{
-							ProbeHandler: corev1.ProbeHandler{
-								Exec: &corev1.ExecAction{
-									Command: []string{"/bin/sh", "-c", "/usr/bin/lavinmqctl status || /usr/bin/lavinmqctl status | grep -q follower"},
-								},
+						// The startup probe gates the liveness and readiness probes until it succeeds.
+						StartupProbe:   b.startupProbe(),
+						LivenessProbe:  b.livenessProbe(),
+						ReadinessProbe: b.readinessProbe(),
+						Lifecycle: &corev1.Lifecycle{
+							PreStop: &corev1.LifecycleHandler{
+								Exec: &corev1.ExecAction{Command: []string{"/bin/sh", "-c", b.preStopScript()}},
 							},
-							InitialDelaySeconds: 5,
-							PeriodSeconds:       10,
 						},
 					},
 				},
@@ -182,22 +295,85 @@ func (b *StatefulSetReconciler) appendSpec(sts *appsv1.StatefulSet) *appsv1.Stat
 				},
 			},
 		},
-		VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
-			{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "data",
-					Namespace: b.Instance.Namespace,
-				},
-				Spec: b.Instance.Spec.DataVolumeClaimSpec,
+	}
+
+	for _, claim := range b.volumeClaims() {
+		sts.Spec.VolumeClaimTemplates = append(sts.Spec.VolumeClaimTemplates, corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      claim.name,
+				Namespace: b.Instance.Namespace,
 			},
-		},
+			Spec: claim.spec,
+		})
 	}
 
 	return sts
 }
+
+// preStopScript returns the preStop hook of the lavinmq container. A clustered pod waits for a follower to take
+// over the leadership, at most until shortly before the grace period ends and the container gets SIGKILL.
+func (b *StatefulSetReconciler) preStopScript() string {
+	if !b.Instance.Spec.Clustering.Enabled() {
+		return preStopScript
+	}
+
+	gracePeriod := ptr.Deref(b.Instance.Spec.TerminationGracePeriodSeconds,
+		cloudamqpcomv1beta1.DefaultTerminationGracePeriodSeconds)
+	deadline := max(gracePeriod-shutdownGracePeriodSeconds, 1)
+
+	body, err := lavinmq.LeaderRangeRequest(b.Instance)
+	if err != nil {
+		return preStopScript
+	}
+
+	endpoints := []string{}
+	for _, endpoint := range b.Instance.Spec.Clustering.EtcdEndpoints {
+		endpoints = append(endpoints, "'"+strings.ReplaceAll(lavinmq.EndpointURL(endpoint), "'", `'\''`)+"'")
+	}
+
+	return fmt.Sprintf(leaderHandoverScript, deadline, strings.Join(endpoints, " "), body)
+}
+
+// podLabels returns the labels of the pods, the labels of the LavinMQ and spec.podLabels with the selector
+// labels on top.
+func (b *StatefulSetReconciler) podLabels() map[string]string {
+	labels := map[string]string{}
+	maps.Copy(labels, b.Instance.Labels)
+	maps.Copy(labels, b.Instance.Spec.PodLabels)
+	maps.Copy(labels, utils.SelectorLabels(b.Instance))
+
+	return labels
+}
+
+// podAnnotations returns spec.podAnnotations, the annotations set by the operator are added later on and
+// take precedence.
+func (b *StatefulSetReconciler) podAnnotations() map[string]string {
+	annotations := map[string]string{}
+	maps.Copy(annotations, b.Instance.Spec.PodAnnotations)
+
+	return annotations
+}
+
+// appendVolumes mounts the additional volumes, the ones backed by PVCs come from the volume claim templates.
+func (b *StatefulSetReconciler) appendVolumes(sts *appsv1.StatefulSet) {
+	podSpec := &sts.Spec.Template.Spec
+	for _, volume := range b.Instance.Spec.Persistence.Volumes {
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      volume.Name,
+			MountPath: volume.MountPath,
+		})
+
+		if volume.EmptyDir != nil {
+			podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+				Name:         volume.Name,
+				VolumeSource: corev1.VolumeSource{EmptyDir: volume.EmptyDir},
+			})
+		}
+	}
+}
 func (b *StatefulSetReconciler) portsFromSpec() []corev1.ContainerPort {
 	ports := []corev1.ContainerPort{}
-	if b.Instance.Spec.EtcdEndpoints != nil {
+	if b.Instance.Spec.Clustering.Enabled() {
 		ports = appendContainerPort(ports, 5679, "clustering")
 	}
 
@@ -255,7 +431,7 @@ func (b *StatefulSetReconciler) cliArgs() []string {
 }
 
 func (b *StatefulSetReconciler) appendTlsConfig(sts *appsv1.StatefulSet) {
-	if b.Instance.Spec.TlsSecret == nil {
+	if b.Instance.Spec.TLS == nil {
 		return
 	}
 
@@ -273,7 +449,7 @@ func (b *StatefulSetReconciler) appendTlsConfig(sts *appsv1.StatefulSet) {
 			Name: "tls",
 			VolumeSource: corev1.VolumeSource{
 				Secret: &corev1.SecretVolumeSource{
-					SecretName: b.Instance.Spec.TlsSecret.Name,
+					SecretName: b.Instance.Spec.TLS.SecretName,
 				},
 			},
 		},
@@ -281,114 +457,241 @@ func (b *StatefulSetReconciler) appendTlsConfig(sts *appsv1.StatefulSet) {
 }
 
 // Used to check if the configmap has changed and restarts the pods if there are any config changes by setting a annotation.
+// Reloadable keys are left out of the hash, changing only them doesn't restart the pods.
 func (b *StatefulSetReconciler) setConfigHashAnnotation(ctx context.Context, sts *appsv1.StatefulSet) error {
+	data, err := b.deployedConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	restart, err := restartConfig(data)
+	if err != nil {
+		b.Logger.Error(err, "Failed to parse config", "name", b.Instance.Name)
+		return err
+	}
+
+	if sts.Spec.Template.ObjectMeta.Annotations == nil {
+		sts.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
+	}
+
+	sts.Spec.Template.ObjectMeta.Annotations[configHashAnnotation] = configHash(restart)
+
+	return nil
+}
+
+// deployedConfig returns the config file in the ConfigMap of the instance.
+func (reconciler *ResourceReconciler) deployedConfig(ctx context.Context) (string, error) {
 	configMap := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      b.Instance.Name,
-			Namespace: b.Instance.Namespace,
+			Name:      reconciler.Instance.Name,
+			Namespace: reconciler.Instance.Namespace,
 		},
 	}
 
-	if err := b.GetItem(ctx, configMap); err != nil {
-		b.Logger.Error(err, "Failed to fetch ConfigMap", "name", configMap.Name, "namespace", configMap.Namespace)
-		return err
+	if err := reconciler.GetItem(ctx, configMap); err != nil {
+		reconciler.Logger.Error(err, "Failed to fetch ConfigMap", "name", configMap.Name, "namespace", configMap.Namespace)
+		return "", err
 	}
 
 	data, exists := configMap.Data[ConfigFileName]
 	if !exists {
 		err := fmt.Errorf("ConfigMap is missing required key: %s", ConfigFileName)
-		b.Logger.Error(err, "ConfigMap is missing required key", "key", ConfigFileName, "name", configMap.Name, "namespace", configMap.Namespace)
-		return err
+		reconciler.Logger.Error(err, "ConfigMap is missing required key", "key", ConfigFileName, "name", configMap.Name, "namespace", configMap.Namespace)
+		return "", err
 	}
 
-	hash := md5.Sum([]byte(data))
-	if sts.Spec.Template.ObjectMeta.Annotations == nil {
-		sts.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
+	return data, nil
+}
+
+func configHash(config string) string {
+	hash := md5.Sum([]byte(config))
+	return hex.EncodeToString(hash[:])
+}
+
+// volumeClaimTemplateOutdated reports whether the volume claim templates of the live statefulset have to be
+// replaced. That's the case when volumes backed by PVCs were added or removed. When the StorageClass of the data
+// volume changed, the storage migration then moves the pods to volumes created from the new template. Or when a
+// template is smaller than the requested size while all existing PVCs have been expanded, so the template can be
+// updated without new pods getting volumes of a different size.
+func (b *StatefulSetReconciler) volumeClaimTemplateOutdated(ctx context.Context, live *appsv1.StatefulSet) (bool, error) {
+	claims := b.volumeClaims()
+	if len(claims) != len(live.Spec.VolumeClaimTemplates) {
+		return true, nil
 	}
 
-	sts.Spec.Template.ObjectMeta.Annotations["config-hash"] = hex.EncodeToString(hash[:])
+	for _, claim := range claims {
+		index := slices.IndexFunc(live.Spec.VolumeClaimTemplates, func(pvc corev1.PersistentVolumeClaim) bool {
+			return pvc.Name == claim.name
+		})
+		if index == -1 {
+			return true, nil
+		}
 
-	return nil
+		template := live.Spec.VolumeClaimTemplates[index].Spec
+		if claim.name == "data" && storageClassChanged(template.StorageClassName, claim.spec.StorageClassName) {
+			return true, nil
+		}
+
+		requested := claim.spec.Resources.Requests.Storage()
+		if template.Resources.Requests.Storage().Cmp(*requested) >= 0 {
+			continue
+		}
+
+		expanded, err := b.claimsExpanded(ctx, live, claim.name, requested)
+		if err != nil || expanded {
+			return expanded, err
+		}
+	}
+
+	return false, nil
 }
 
-func (b *StatefulSetReconciler) updateFields(ctx context.Context, sts *appsv1.StatefulSet) error {
-	if *sts.Spec.Replicas != int32(b.Instance.Spec.Replicas) {
-		b.Logger.Info("Replicas changed", "old", sts.Spec.Replicas, "new", b.Instance.Spec.Replicas)
-		// TODO: Add support for scaling.
-		sts.Spec.Replicas = &b.Instance.Spec.Replicas
+// claimsExpanded reports whether all existing PVCs of the volume claim template name have the requested size.
+func (b *StatefulSetReconciler) claimsExpanded(ctx context.Context, live *appsv1.StatefulSet, name string, requested *resource.Quantity) (bool, error) {
+	for i := range int(ptr.Deref(live.Spec.Replicas, 0)) {
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%s-%d", name, live.Name, i),
+				Namespace: live.Namespace,
+			},
+		}
+		if err := b.GetItem(ctx, pvc); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+
+		if pvc.Spec.Resources.Requests.Storage().Cmp(*requested) < 0 {
+			return false, nil
+		}
 	}
 
-	b.diffTemplate(&sts.Spec.Template.Spec)
+	return true, nil
+}
 
-	if err := b.setConfigHashAnnotation(ctx, sts); err != nil {
+// updateStrategy hands the rollout of an image upgrade or a requested restart of a cluster to the upgrade and
+// restart reconcilers, which pick the order of the pods. Otherwise the StatefulSet rolls the pods itself.
+func (b *StatefulSetReconciler) updateStrategy() appsv1.StatefulSetUpdateStrategy {
+	if b.orderedUpgrade() || b.orderedRestart() {
+		return appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType}
+	}
+
+	return appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType}
+}
+
+// switchToOnDelete removes the rolling update settings, defaulted by the API server, that aren't allowed with the
+// OnDelete update strategy. As they're not owned by the operator a server-side apply would keep them.
+func (b *StatefulSetReconciler) switchToOnDelete(ctx context.Context, live *appsv1.StatefulSet) error {
+	patch := []byte(`{"spec":{"updateStrategy":{"type":"OnDelete","rollingUpdate":null}}}`)
+	if err := b.Client.Patch(ctx, live, client.RawPatch(types.MergePatchType, patch), client.FieldOwner(FieldOwner)); err != nil {
+		b.Logger.Error(err, "Failed to switch StatefulSet to the OnDelete update strategy", "name", live.Name)
 		return err
 	}
 
 	return nil
 }
 
-func (b *StatefulSetReconciler) diffTemplate(old *corev1.PodSpec) {
-	// Pointer the old as that's the object we're mutating
-	oldContainer := &old.Containers[0]
+// deleteOrphaningPods deletes the statefulset but keeps its pods and PVCs, used to change immutable fields.
+// The recreated statefulset adopts the pods again, they're only restarted if the pod template changed.
+func (b *StatefulSetReconciler) deleteOrphaningPods(ctx context.Context, live *appsv1.StatefulSet) error {
+	b.Logger.Info("Deleting StatefulSet with orphaned pods to recreate it", "name", live.Name)
+	err := b.Client.Delete(ctx, live, client.PropagationPolicy(metav1.DeletePropagationOrphan),
+		client.Preconditions{UID: &live.UID, ResourceVersion: &live.ResourceVersion})
+	if err != nil && !apierrors.IsNotFound(err) {
+		b.Logger.Error(err, "Failed to delete StatefulSet", "name", live.Name)
+		return err
+	}
+
+	return nil
+}
 
-	if oldContainer.Image != b.Instance.Spec.Image {
-		oldContainer.Image = b.Instance.Spec.Image
+// migrateSelector recreates a StatefulSet created by an earlier version, whose selector is immutable and may
+// match the pods of other instances. Its pods are labeled with the new selector first, so the recreated
+// StatefulSet adopts them rather than failing to create pods with their names.
+func (b *StatefulSetReconciler) migrateSelector(ctx context.Context, live *appsv1.StatefulSet, selector map[string]string) error {
+	b.Logger.Info("Migrating StatefulSet to a new selector", "name", live.Name, "from", live.Spec.Selector.MatchLabels, "to", selector)
+	if err := b.labelPods(ctx, live, selector); err != nil {
+		return err
 	}
 
-	if !resource_utils.EqualResourceRequirements(oldContainer.Resources, b.Instance.Spec.Resources) {
-		b.Logger.Info("Container resources changed, updating")
-		oldContainer.Resources = b.Instance.Spec.Resources
+	return b.deleteOrphaningPods(ctx, live)
+}
+
+// labelPods adds labels to the pods owned by the StatefulSet, keeping the labels they have.
+func (reconciler *ResourceReconciler) labelPods(ctx context.Context, sts *appsv1.StatefulSet, labels map[string]string) error {
+	pods := &corev1.PodList{}
+	if err := reconciler.Client.List(ctx, pods, client.InNamespace(sts.Namespace), client.MatchingLabels(sts.Spec.Selector.MatchLabels)); err != nil {
+		reconciler.Logger.Error(err, "Failed to list pods", "name", sts.Name)
+		return err
 	}
 
-	cliArgs := b.cliArgs()
-	// TODO: Expand this to own methods and granular checks
-	if !reflect.DeepEqual(oldContainer.Args, cliArgs) {
-		b.Logger.Info("cli args changed, updating")
-		oldContainer.Args = cliArgs
+	for _, pod := range pods.Items {
+		if owner := metav1.GetControllerOf(&pod); owner == nil || owner.UID != sts.UID {
+			continue
+		}
+
+		original := pod.DeepCopy()
+		if pod.Labels == nil {
+			pod.Labels = map[string]string{}
+		}
+		maps.Copy(pod.Labels, labels)
+		if maps.Equal(original.Labels, pod.Labels) {
+			continue
+		}
+		if err := reconciler.Client.Patch(ctx, &pod, client.MergeFrom(original)); err != nil {
+			reconciler.Logger.Error(err, "Failed to label pod", "name", pod.Name)
+			return err
+		}
 	}
 
-	if !reflect.DeepEqual(oldContainer.Ports, b.portsFromSpec()) {
-		b.Logger.Info("ports changed, updating")
-		oldContainer.Ports = b.portsFromSpec()
+	return nil
+}
+
+// recordChanges emits events and metrics for the changes applied to the statefulset.
+func (b *StatefulSetReconciler) recordChanges(old, updated *appsv1.StatefulSet) {
+	if *old.Spec.Replicas != *updated.Spec.Replicas {
+		b.normalEventf(EventReasonReplicasChanged, "Changed replicas from %d to %d", *old.Spec.Replicas, *updated.Spec.Replicas)
 	}
 
-	if !reflect.DeepEqual(old.NodeSelector, b.Instance.Spec.NodeSelector) {
-		b.Logger.Info("nodeSelector changed, updating")
-		old.NodeSelector = b.Instance.Spec.NodeSelector
+	oldHash := old.Spec.Template.Annotations[configHashAnnotation]
+	if oldHash != "" && oldHash != updated.Spec.Template.Annotations[configHashAnnotation] {
+		b.Logger.Info("Config changed, rolling restart triggered")
+		metrics.RollingRestarts.WithLabelValues(b.Instance.Namespace, b.Instance.Name).Inc()
+		b.normalEventf(EventReasonRollingRestart, "Configuration changed, rolling restart of StatefulSet %s triggered", updated.Name)
 	}
 
-	index := slices.IndexFunc(old.Volumes, func(v corev1.Volume) bool {
+	oldRestartedAt := old.Spec.Template.Annotations[restartedAtAnnotation]
+	if newRestartedAt := updated.Spec.Template.Annotations[restartedAtAnnotation]; oldRestartedAt != newRestartedAt {
+		b.Logger.Info("Restart requested, rolling restart triggered", "restartedAt", newRestartedAt)
+		metrics.RollingRestarts.WithLabelValues(b.Instance.Namespace, b.Instance.Name).Inc()
+		b.normalEventf(EventReasonRollingRestart, "Restart requested at %s, rolling restart of StatefulSet %s triggered",
+			newRestartedAt, updated.Name)
+	}
+
+	oldSecret, newSecret := tlsSecretName(&old.Spec.Template.Spec), tlsSecretName(&updated.Spec.Template.Spec)
+	if oldSecret != newSecret {
+		b.normalEventf(EventReasonTLSSecretChanged, "Switched TLS secret from %q to %q", oldSecret, newSecret)
+	}
+}
+
+func tlsSecretName(spec *corev1.PodSpec) string {
+	index := slices.IndexFunc(spec.Volumes, func(v corev1.Volume) bool {
 		return v.Name == "tls"
 	})
-
-	if index != -1 {
-		secretName := old.Volumes[index].VolumeSource.Secret.SecretName
-		// Checks if the secret name is the same as the one in the instance spec
-		if b.Instance.Spec.TlsSecret != nil && b.Instance.Spec.TlsSecret.Name != secretName {
-			b.Logger.Info("tls secret changed, updating")
-			old.Volumes[index] = corev1.Volume{
-				Name: "tls",
-				VolumeSource: corev1.VolumeSource{
-					Secret: &corev1.SecretVolumeSource{
-						SecretName: b.Instance.Spec.TlsSecret.Name,
-					},
-				},
-			}
-		}
-	} else if b.Instance.Spec.TlsSecret != nil {
-		b.Logger.Info("adding tls secret to volumes")
-		old.Volumes = append(old.Volumes, corev1.Volume{
-			Name: "tls",
-			VolumeSource: corev1.VolumeSource{
-				Secret: &corev1.SecretVolumeSource{
-					SecretName: b.Instance.Spec.TlsSecret.Name,
-				},
-			},
-		})
+	if index == -1 || spec.Volumes[index].Secret == nil {
+		return ""
 	}
+
+	return spec.Volumes[index].Secret.SecretName
 }
 
 // Name returns the name of the statefulset reconciler
 func (b *StatefulSetReconciler) Name() string {
 	return "statefulset"
 }
+
+// DependsOn returns the reconcilers that must succeed first, the config hash is calculated from the ConfigMap.
+func (b *StatefulSetReconciler) DependsOn() []string {
+	return []string{b.ConfigReconciler().Name()}
+}
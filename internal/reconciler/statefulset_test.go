@@ -1,18 +1,34 @@
 package reconciler_test
 
 import (
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
-	cloudamqpcomv1alpha1 "github.com/cloudamqp/lavinmq-operator/api/v1alpha1"
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
 	"github.com/cloudamqp/lavinmq-operator/internal/reconciler"
 	testutils "github.com/cloudamqp/lavinmq-operator/internal/test_utils"
 )
@@ -53,6 +69,204 @@ func TestStatefulSetReconciler(t *testing.T) {
 	assert.Equal(t, "test-image:latest2", sts.Spec.Template.Spec.Containers[0].Image)
 }
 
+func TestProbes(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	rc := &reconciler.StatefulSetReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+		},
+	}
+
+	instance.Spec.Probes.Readiness = &cloudamqpcomv1beta1.ProbeTimings{
+		PeriodSeconds:    ptr.To(int32(5)),
+		FailureThreshold: ptr.To(int32(6)),
+	}
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	sts := &appsv1.StatefulSet{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+
+	container := sts.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "/health/liveness", container.LivenessProbe.HTTPGet.Path)
+	assert.Equal(t, int32(10), container.LivenessProbe.PeriodSeconds)
+	assert.Equal(t, "/health/liveness", container.StartupProbe.HTTPGet.Path)
+	assert.Equal(t, int32(30), container.StartupProbe.FailureThreshold)
+
+	assert.Equal(t, "/health/readiness", container.ReadinessProbe.HTTPGet.Path)
+	assert.Equal(t, int32(5), container.ReadinessProbe.InitialDelaySeconds)
+	assert.Equal(t, int32(5), container.ReadinessProbe.PeriodSeconds)
+	assert.Equal(t, int32(6), container.ReadinessProbe.FailureThreshold)
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	rc := &reconciler.StatefulSetReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+		},
+	}
+
+	instance.Spec.TerminationGracePeriodSeconds = ptr.To(int64(300))
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	sts := &appsv1.StatefulSet{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+
+	assert.Equal(t, int64(300), *sts.Spec.Template.Spec.TerminationGracePeriodSeconds)
+
+	calls, err := runPreStop(t, sts, "leader", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lavinmqctl stop_app"}, calls, "A single node only stops the app")
+}
+
+func TestLeaderHandover(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	instance.Spec.Replicas = 3
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379", "http://etcd-1:2379"}
+	// The default user is only allowed on loopback, the handover mustn't depend on the management API.
+	instance.Spec.Config.Mgmt.Port = -1
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	rc := &reconciler.StatefulSetReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+		},
+	}
+
+	instance.Spec.TerminationGracePeriodSeconds = ptr.To(int64(8))
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	sts := &appsv1.StatefulSet{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+
+	t.Run("follower", func(t *testing.T) {
+		calls, err := runPreStop(t, sts, "follower", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"lavinmqctl status"}, calls, "A follower is stopped right away")
+	})
+
+	t.Run("waits for another pod to hold the leader key", func(t *testing.T) {
+		calls, err := runPreStop(t, sts, "leader", 2)
+		assert.NoError(t, err)
+		request := "-sf -m 2 -X POST http://etcd-0:2379/v3/kv/range -d " +
+			`{"key":"` + base64.StdEncoding.EncodeToString([]byte(instance.Name+"/leader/")) + `",` +
+			`"range_end":"` + base64.StdEncoding.EncodeToString([]byte(instance.Name+"/leader0")) + `",` +
+			`"limit":1,"sort_order":"ASCEND","sort_target":"CREATE"}`
+		assert.Equal(t, []string{
+			"lavinmqctl status",
+			"lavinmqctl stop_app",
+			"curl " + request,
+			"curl " + request,
+			"curl " + request,
+		}, calls, "The leader polls etcd until its own key is released")
+	})
+
+	t.Run("bounded by the grace period", func(t *testing.T) {
+		start := time.Now()
+		calls, err := runPreStop(t, sts, "leader", -1)
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(start), 8*time.Second, "The hook must return before the grace period ends")
+		assert.Contains(t, calls, "lavinmqctl stop_app")
+	})
+}
+
+// runPreStop runs the preStop hook of sts with stubs of lavinmqctl and curl, and returns their calls. The local
+// node reports role. Etcd answers the leader range request like its gRPC gateway, with the key of the pod itself
+// for the first leaderAfter requests and then with the key of another pod, never if negative.
+func runPreStop(t *testing.T, sts *appsv1.StatefulSet, role string, leaderAfter int) ([]string, error) {
+	t.Helper()
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls")
+	response := func(pod string) string {
+		uri := fmt.Sprintf("tcp://%s.%s.%s.svc.cluster.local:5679", pod, sts.Name, sts.Namespace)
+		return `{"header":{"cluster_id":"14841639068965178418","member_id":"10276657743932975437","revision":"42","raft_term":"3"},` +
+			`"kvs":[{"key":"` + base64.StdEncoding.EncodeToString([]byte(sts.Name+"/leader/694d7a8c1f2e4b03")) + `",` +
+			`"create_revision":"7","mod_revision":"7","version":"1",` +
+			`"value":"` + base64.StdEncoding.EncodeToString([]byte(uri)) + `","lease":"7587883136591458051"}],"count":"2"}`
+	}
+	stubs := map[string]string{
+		"lavinmqctl": `echo "lavinmqctl $*" >> "$CALLS"
+[ "$1" = status ] && echo "$ROLE"
+exit 0`,
+		"curl": `echo "curl $*" >> "$CALLS"
+polls=$(grep -c "^curl" "$CALLS")
+if [ "$LEADER_AFTER" -ge 0 ] && [ "$polls" -gt "$LEADER_AFTER" ]; then
+  echo '` + response(sts.Name+"-1") + `'
+else
+  echo '` + response(sts.Name+"-0") + `'
+fi`,
+	}
+	for name, script := range stubs {
+		err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"+script+"\n"), 0o755)
+		assert.NoError(t, err)
+	}
+
+	hook := sts.Spec.Template.Spec.Containers[0].Lifecycle.PreStop.Exec.Command
+	cmd := exec.CommandContext(t.Context(), hook[0], hook[1:]...)
+	cmd.Env = append(os.Environ(),
+		"PATH="+bin+":"+os.Getenv("PATH"),
+		"POD_NAME="+sts.Name+"-0",
+		"CALLS="+calls,
+		"ROLE="+role,
+		"LEADER_AFTER="+strconv.Itoa(leaderAfter),
+	)
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(calls)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSpace(string(content)), "\n"), nil
+}
+
 func TestCreateContainerResources(t *testing.T) {
 	t.Parallel()
 	Resources := corev1.ResourceRequirements{
@@ -240,7 +454,197 @@ func TestConfigHashAnnotation(t *testing.T) {
 	assert.NotEqual(t, initialHash, updatedHash, "Config hash should change when ConfigMap content changes")
 }
 
-func createConfigMap(t *testing.T, instance *cloudamqpcomv1alpha1.LavinMQ, config string) *corev1.ConfigMap {
+func TestReplicaChangeEvent(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	recorder := record.NewFakeRecorder(10)
+	rc := &reconciler.StatefulSetReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+			Recorder: recorder,
+		},
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	instance.Spec.Replicas = 3
+	err = k8sClient.Update(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to update instance")
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	assert.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Normal ReplicasChanged Changed replicas from 1 to 3", <-recorder.Events)
+}
+
+func TestVolumeClaimTemplateExpansion(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	recorder := record.NewFakeRecorder(10)
+	rc := &reconciler.StatefulSetReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+			Recorder: recorder,
+		},
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data-" + instance.Name + "-0", Namespace: instance.Namespace},
+		Spec:       *instance.Spec.Persistence.DataVolumeClaimSpec.DeepCopy(),
+	}
+	assert.NoError(t, k8sClient.Create(t.Context(), pvc))
+	pvc.Status.Phase = corev1.ClaimBound
+	assert.NoError(t, k8sClient.Status().Update(t.Context(), pvc))
+
+	instance.Spec.Persistence.DataVolumeClaimSpec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("20Gi")
+	err = k8sClient.Update(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to update instance")
+
+	t.Log("Keeping the StatefulSet while the PVC is not expanded")
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	sts := &appsv1.StatefulSet{}
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	assert.NoError(t, k8sClient.Get(t.Context(), key, sts))
+	assert.Nil(t, sts.DeletionTimestamp)
+	assert.Equal(t, "10Gi", sts.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests.Storage().String())
+
+	t.Log("Recreating the StatefulSet once the PVC is expanded")
+	pvc.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("20Gi")
+	assert.NoError(t, k8sClient.Update(t.Context(), pvc))
+
+	result, err := rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+	assert.NotZero(t, result.RequeueAfter)
+
+	assert.NoError(t, k8sClient.Get(t.Context(), key, sts))
+	assert.NotNil(t, sts.DeletionTimestamp)
+	assert.Contains(t, sts.Finalizers, metav1.FinalizerOrphanDependents)
+
+	// There's no garbage collector in the test environment to orphan the pods and remove the finalizer
+	sts.Finalizers = nil
+	assert.NoError(t, k8sClient.Update(t.Context(), sts))
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	sts = &appsv1.StatefulSet{}
+	assert.NoError(t, k8sClient.Get(t.Context(), key, sts))
+	assert.Nil(t, sts.DeletionTimestamp)
+	assert.Equal(t, "20Gi", sts.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests.Storage().String())
+
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Normal StatefulSetRecreated")
+}
+
+func TestAdditionalVolumes(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	recorder := record.NewFakeRecorder(10)
+	rc := &reconciler.StatefulSetReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+			Recorder: recorder,
+		},
+	}
+
+	instance.Spec.Persistence.Volumes = []cloudamqpcomv1beta1.VolumeSpec{
+		{Name: "scratch", MountPath: "/tmp", EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}},
+	}
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	sts := &appsv1.StatefulSet{}
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	assert.NoError(t, k8sClient.Get(t.Context(), key, sts))
+	assert.Contains(t, sts.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: "scratch", MountPath: "/tmp"})
+	assert.Contains(t, sts.Spec.Template.Spec.Volumes, corev1.Volume{
+		Name:         "scratch",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}},
+	})
+	assert.Len(t, sts.Spec.VolumeClaimTemplates, 1)
+
+	t.Log("Recreating the StatefulSet when a volume claim is added")
+	instance.Spec.Persistence.Volumes = append(instance.Spec.Persistence.Volumes, cloudamqpcomv1beta1.VolumeSpec{
+		Name:            "segments",
+		MountPath:       "/var/lib/lavinmq/segments",
+		VolumeClaimSpec: instance.Spec.Persistence.DataVolumeClaimSpec.DeepCopy(),
+	})
+	err = k8sClient.Update(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to update instance")
+
+	result, err := rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+	assert.NotZero(t, result.RequeueAfter)
+
+	assert.NoError(t, k8sClient.Get(t.Context(), key, sts))
+	assert.NotNil(t, sts.DeletionTimestamp)
+
+	// There's no garbage collector in the test environment to orphan the pods and remove the finalizer
+	sts.Finalizers = nil
+	assert.NoError(t, k8sClient.Update(t.Context(), sts))
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	sts = &appsv1.StatefulSet{}
+	assert.NoError(t, k8sClient.Get(t.Context(), key, sts))
+	assert.Len(t, sts.Spec.VolumeClaimTemplates, 2)
+	assert.Equal(t, "segments", sts.Spec.VolumeClaimTemplates[1].Name)
+	assert.Contains(t, sts.Spec.Template.Spec.Containers[0].VolumeMounts,
+		corev1.VolumeMount{Name: "segments", MountPath: "/var/lib/lavinmq/segments"})
+
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Normal StatefulSetRecreated")
+}
+
+func createConfigMap(t *testing.T, instance *cloudamqpcomv1beta1.LavinMQ, config string) *corev1.ConfigMap {
 	// Create initial ConfigMap
 	configMap := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -261,3 +665,394 @@ func deleteConfigMap(t *testing.T, configMap *corev1.ConfigMap) {
 	err := k8sClient.Delete(t.Context(), configMap)
 	assert.NoErrorf(t, err, "Failed to delete ConfigMap")
 }
+
+func TestMaintenance(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = resourceReconciler.ConfigReconciler().Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile config")
+	_, err = resourceReconciler.StatefulSetReconciler().Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile statefulset")
+
+	sts := &appsv1.StatefulSet{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+	configHash := sts.Spec.Template.Annotations["config-hash"]
+
+	t.Log("Holding back config changes and scaling while in maintenance")
+	instance.Spec.Maintenance = true
+	instance.Spec.Replicas = 3
+	instance.Spec.Config.Main.LogLevel = "debug"
+
+	_, err = resourceReconciler.ConfigReconciler().Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile config")
+	_, err = resourceReconciler.StatefulSetReconciler().Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile statefulset")
+
+	configMap := &corev1.ConfigMap{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, configMap)
+	assert.NoErrorf(t, err, "Failed to get configmap")
+	assert.NotContains(t, configMap.Data[reconciler.ConfigFileName], "log_level")
+
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+	assert.Equal(t, int32(1), *sts.Spec.Replicas)
+	assert.Equal(t, configHash, sts.Spec.Template.Annotations["config-hash"])
+
+	t.Log("Rolling out the changes once maintenance is cleared")
+	instance.Spec.Maintenance = false
+
+	_, err = resourceReconciler.ConfigReconciler().Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile config")
+	_, err = resourceReconciler.StatefulSetReconciler().Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile statefulset")
+
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, configMap)
+	assert.NoErrorf(t, err, "Failed to get configmap")
+	assert.Contains(t, configMap.Data[reconciler.ConfigFileName], "log_level = debug")
+
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+	assert.Equal(t, int32(3), *sts.Spec.Replicas)
+	assert.NotEqual(t, configHash, sts.Spec.Template.Annotations["config-hash"])
+}
+
+func TestMaintenanceKeepsPods(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{Replicas: ptr.To(int32(3))})
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+	instance.Spec.Config.Amqp.Port = 5672
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+		Leaders:  staticLeader(instance.Name + "-2"),
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	reconcileAll := func() {
+		for _, rc := range []reconciler.Reconciler{
+			resourceReconciler.ConfigReconciler(),
+			resourceReconciler.HeadlessServiceReconciler(),
+			resourceReconciler.StatefulSetReconciler(),
+			resourceReconciler.UpgradeReconciler(),
+			resourceReconciler.RestartReconciler(),
+		} {
+			_, err := rc.Reconcile(t.Context())
+			assert.NoErrorf(t, err, "Failed to reconcile %s", rc.Name())
+		}
+	}
+	reconcileAll()
+	for i := range 3 {
+		createPod(t, instance, i, instance.Spec.Image, true)
+	}
+
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	sts := &appsv1.StatefulSet{}
+	assert.NoError(t, k8sClient.Get(t.Context(), key, sts))
+	service := &corev1.Service{}
+	assert.NoError(t, k8sClient.Get(t.Context(), key, service))
+
+	t.Log("Changing the image, a listener port and requesting a restart in maintenance")
+	instance.Spec.Maintenance = true
+	instance.Spec.Image = "cloudamqp/lavinmq:2.5.0"
+	instance.Spec.Config.Amqp.Port = 5673
+	instance.Annotations = map[string]string{cloudamqpcomv1beta1.RestartedAtAnnotation: "2025-01-01T00:00:00Z"}
+	reconcileAll()
+
+	maintained := &appsv1.StatefulSet{}
+	assert.NoError(t, k8sClient.Get(t.Context(), key, maintained))
+	assert.Equal(t, sts.Spec.Template, maintained.Spec.Template, "The pod template must not change")
+	maintainedService := &corev1.Service{}
+	assert.NoError(t, k8sClient.Get(t.Context(), key, maintainedService))
+	assert.Equal(t, service.Spec.Ports, maintainedService.Spec.Ports, "The Service must keep the ports LavinMQ listens on")
+	for i := range 3 {
+		pod := &corev1.Pod{}
+		err := k8sClient.Get(t.Context(), types.NamespacedName{Name: fmt.Sprintf("%s-%d", instance.Name, i), Namespace: instance.Namespace}, pod)
+		assert.NoError(t, err)
+		assert.Nil(t, pod.DeletionTimestamp, "No pod is deleted in maintenance")
+	}
+
+	t.Log("Rolling out the changes once maintenance is cleared")
+	instance.Spec.Maintenance = false
+	reconcileAll()
+
+	assert.NoError(t, k8sClient.Get(t.Context(), key, maintained))
+	assert.Equal(t, "cloudamqp/lavinmq:2.5.0", maintained.Spec.Template.Spec.Containers[0].Image)
+	assert.NoError(t, k8sClient.Get(t.Context(), key, maintainedService))
+	assert.True(t, slices.ContainsFunc(maintainedService.Spec.Ports, func(port corev1.ServicePort) bool { return port.Port == 5673 }))
+}
+
+func TestPodLabelsAndAnnotations(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	instance.Labels = map[string]string{"team": "messaging"}
+	instance.Spec.PodLabels = map[string]string{"sidecar.istio.io/inject": "false", "app.kubernetes.io/name": "mine"}
+	instance.Spec.PodAnnotations = map[string]string{"prometheus.io/scrape": "true", "config-hash": "mine"}
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	rc := &reconciler.StatefulSetReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+		},
+	}
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	sts := &appsv1.StatefulSet{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+
+	assert.Equal(t, map[string]string{
+		"app.kubernetes.io/name":       "lavinmq-operator",
+		"app.kubernetes.io/managed-by": "LavinMQController",
+		"app.kubernetes.io/instance":   instance.Name,
+		"app.kubernetes.io/component":  "broker",
+	}, sts.Spec.Selector.MatchLabels)
+
+	podLabels := sts.Spec.Template.Labels
+	assert.Equal(t, "messaging", podLabels["team"])
+	assert.Equal(t, "false", podLabels["sidecar.istio.io/inject"])
+	assert.Equal(t, "lavinmq-operator", podLabels["app.kubernetes.io/name"], "Expected the selector labels to win over podLabels")
+	assert.Equal(t, instance.Name, podLabels[cloudamqpcomv1beta1.InstanceLabel])
+
+	podAnnotations := sts.Spec.Template.Annotations
+	assert.Equal(t, "true", podAnnotations["prometheus.io/scrape"])
+	assert.NotEqual(t, "mine", podAnnotations["config-hash"], "Expected the operator annotations to win over podAnnotations")
+}
+
+func TestLabelChangesKeepSelector(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	instance.Labels = map[string]string{"team": "messaging"}
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	rc := &reconciler.StatefulSetReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+		},
+	}
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	instance.Labels = map[string]string{"team": "platform"}
+	result, err := rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance after changing its labels")
+	assert.Zero(t, result.RequeueAfter, "Expected the StatefulSet not to be recreated")
+
+	sts := &appsv1.StatefulSet{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+	assert.NotContains(t, sts.Spec.Selector.MatchLabels, "team")
+	assert.Equal(t, "platform", sts.Spec.Template.Labels["team"])
+	assert.Equal(t, "platform", sts.Labels["team"])
+}
+
+func TestSelectorMigration(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+	instance.Labels = map[string]string{"team": "messaging"}
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	recorder := record.NewFakeRecorder(10)
+	rc := &reconciler.StatefulSetReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+			Recorder: recorder,
+		},
+	}
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	// Recreate the StatefulSet the way earlier versions did, selecting on the shared labels and the ones of
+	// the LavinMQ.
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	sts := &appsv1.StatefulSet{}
+	assert.NoError(t, k8sClient.Get(t.Context(), key, sts))
+	assert.NoError(t, k8sClient.Delete(t.Context(), sts))
+	legacy := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: sts.Name, Namespace: sts.Namespace, Labels: sts.Labels},
+		Spec:       *sts.Spec.DeepCopy(),
+	}
+	legacyLabels := map[string]string{
+		"app.kubernetes.io/name":       "lavinmq-operator",
+		"app.kubernetes.io/managed-by": "LavinMQController",
+		"team":                         "messaging",
+	}
+	legacy.Spec.Selector.MatchLabels = legacyLabels
+	legacy.Spec.Template.Labels = maps.Clone(legacyLabels)
+	assert.NoError(t, controllerutil.SetControllerReference(instance, legacy, scheme.Scheme))
+	assert.NoError(t, k8sClient.Create(t.Context(), legacy))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: instance.Name + "-0", Namespace: instance.Namespace, Labels: maps.Clone(legacyLabels)},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "lavinmq", Image: "cloudamqp/lavinmq:2.4.1"}}},
+	}
+	assert.NoError(t, controllerutil.SetControllerReference(legacy, pod, scheme.Scheme))
+	assert.NoError(t, k8sClient.Create(t.Context(), pod))
+	// A pod of another instance matching the legacy selector is left alone.
+	other := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-0", Namespace: instance.Namespace, Labels: maps.Clone(legacyLabels)},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "lavinmq", Image: "cloudamqp/lavinmq:2.4.1"}}},
+	}
+	assert.NoError(t, k8sClient.Create(t.Context(), other))
+
+	t.Log("The headless Service only selects on the new labels once the pods have them")
+	_, err = rc.HeadlessServiceReconciler().Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile headless service")
+	service := &corev1.Service{}
+	assert.NoError(t, k8sClient.Get(t.Context(), key, service))
+	assert.NoError(t, k8sClient.Get(t.Context(), client.ObjectKeyFromObject(pod), pod))
+	assert.True(t, labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(pod.Labels)),
+		"The Service must keep selecting the running pods")
+	assert.True(t, labels.SelectorFromSet(legacyLabels).Matches(labels.Set(pod.Labels)),
+		"The legacy StatefulSet must keep owning its pods")
+
+	result, err := rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+	assert.NotZero(t, result.RequeueAfter)
+
+	assert.NoError(t, k8sClient.Get(t.Context(), client.ObjectKeyFromObject(pod), pod))
+	assert.Equal(t, instance.Name, pod.Labels["app.kubernetes.io/instance"])
+	assert.Equal(t, "broker", pod.Labels["app.kubernetes.io/component"])
+	assert.NoError(t, k8sClient.Get(t.Context(), client.ObjectKeyFromObject(other), other))
+	assert.NotContains(t, other.Labels, "app.kubernetes.io/component")
+
+	assert.NoError(t, k8sClient.Get(t.Context(), key, sts))
+	assert.NotNil(t, sts.DeletionTimestamp)
+	// There's no garbage collector in the test environment to orphan the pods and remove the finalizer
+	sts.Finalizers = nil
+	assert.NoError(t, k8sClient.Update(t.Context(), sts))
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	sts = &appsv1.StatefulSet{}
+	assert.NoError(t, k8sClient.Get(t.Context(), key, sts))
+	assert.Nil(t, sts.DeletionTimestamp)
+	assert.Equal(t, instance.Name, sts.Spec.Selector.MatchLabels["app.kubernetes.io/instance"])
+	assert.NotContains(t, sts.Spec.Selector.MatchLabels, "team")
+
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Normal StatefulSetRecreated")
+}
+
+func TestScaleStatus(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	recorder := record.NewFakeRecorder(10)
+	rc := &reconciler.StatefulSetReconciler{
+		ResourceReconciler: &reconciler.ResourceReconciler{
+			Instance: instance,
+			Scheme:   scheme.Scheme,
+			Client:   k8sClient,
+			Recorder: recorder,
+		},
+	}
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	key := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+	sts := &appsv1.StatefulSet{}
+	assert.NoError(t, k8sClient.Get(t.Context(), key, sts))
+	sts.Status.Replicas = 1
+	assert.NoError(t, k8sClient.Status().Update(t.Context(), sts))
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+	assert.Equal(t, int32(1), instance.Status.Replicas)
+	selector, err := metav1.LabelSelectorAsSelector(sts.Spec.Selector)
+	assert.NoError(t, err)
+	assert.Equal(t, selector.String(), instance.Status.Selector)
+
+	t.Log("Not scaling out without an etcd cluster")
+	instance.Spec.Replicas = 3
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+
+	assert.NoError(t, k8sClient.Get(t.Context(), key, sts))
+	assert.Equal(t, int32(1), *sts.Spec.Replicas)
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Warning ReplicasRejected")
+	assert.True(t, meta.IsStatusConditionTrue(instance.Status.Conditions, reconciler.ConditionReplicasRejected))
+
+	t.Log("Warning only once while the replicas stay rejected")
+	for range 3 {
+		_, err = rc.Reconcile(t.Context())
+		assert.NoErrorf(t, err, "Failed to reconcile instance")
+	}
+	assert.Empty(t, recorder.Events)
+
+	t.Log("Clearing the condition for a StatefulSet that already has the requested replicas")
+	sts.Spec.Replicas = ptr.To(int32(3))
+	assert.NoError(t, k8sClient.Update(t.Context(), sts, client.FieldOwner("kubectl-edit")))
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile instance")
+	assert.Nil(t, meta.FindStatusCondition(instance.Status.Conditions, reconciler.ConditionReplicasRejected))
+}
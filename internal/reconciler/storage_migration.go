@@ -0,0 +1,220 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// How often to check on a pod that is moved to a new volume and resyncing its data.
+const storageMigrationRequeueDelay = 10 * time.Second
+
+// StorageMigrationReconciler moves the data volumes to a new StorageClass. As the StorageClass of a PVC can't
+// be changed, one pod at a time its PVC and the pod itself are deleted. The StatefulSet recreates both, the PVC
+// from the updated volume claim template, and the node resyncs its data from the leader. Followers are migrated
+// first and the leader last, handing over the leadership to a follower already on the new storage.
+type StorageMigrationReconciler struct {
+	*ResourceReconciler
+}
+
+func (reconciler *ResourceReconciler) StorageMigrationReconciler() *StorageMigrationReconciler {
+	return &StorageMigrationReconciler{
+		ResourceReconciler: reconciler,
+	}
+}
+
+func (b *StorageMigrationReconciler) Reconcile(ctx context.Context) (ctrl.Result, error) {
+	storageClass := ptr.Deref(b.Instance.Spec.Persistence.DataVolumeClaimSpec.StorageClassName, "")
+	if storageClass == "" {
+		return ctrl.Result{}, nil
+	}
+
+	if b.Instance.Spec.Maintenance {
+		b.Logger.Info("Instance in maintenance, not migrating storage", "storageClass", storageClass)
+		return ctrl.Result{}, nil
+	}
+
+	pending, err := b.pendingPods(ctx, storageClass)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	previous := b.Instance.Status.StorageMigration
+	if len(pending) == 0 {
+		if previous != nil {
+			b.Logger.Info("Storage migration completed", "storageClass", storageClass)
+			b.normalEventf(EventReasonStorageMigrationCompleted, "Moved all data volumes to StorageClass %s", storageClass)
+		}
+		b.Instance.Status.StorageMigration = nil
+		return ctrl.Result{}, nil
+	}
+
+	progress := &cloudamqpcomv1beta1.StorageMigrationStatus{
+		StorageClassName: storageClass,
+		StartTime:        metav1.Now(),
+	}
+	if previous != nil && previous.StorageClassName == storageClass {
+		progress = previous.DeepCopy()
+	}
+	progress.Total = b.Instance.Spec.Replicas
+	progress.Migrated = progress.Total - int32(len(pending))
+	b.Instance.Status.StorageMigration = progress
+
+	ready, err := b.readyToMigrate(ctx, storageClass)
+	if err != nil || !ready {
+		return ctrl.Result{RequeueAfter: storageMigrationRequeueDelay}, err
+	}
+
+	pod, err := b.nextPodLeaderLast(ctx, pending)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := b.migratePod(ctx, pod); err != nil {
+		return ctrl.Result{}, err
+	}
+	progress.CurrentPod = pod
+
+	return ctrl.Result{RequeueAfter: storageMigrationRequeueDelay}, nil
+}
+
+// pendingPods returns the pods whose data PVC still uses another StorageClass.
+func (b *StorageMigrationReconciler) pendingPods(ctx context.Context, storageClass string) ([]string, error) {
+	pending := []string{}
+	for i := range int(b.Instance.Spec.Replicas) {
+		pod := fmt.Sprintf("%s-%d", b.Instance.Name, i)
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "data-" + pod,
+				Namespace: b.Instance.Namespace,
+			},
+		}
+		if err := b.GetItem(ctx, pvc); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		if storageClassChanged(pvc.Spec.StorageClassName, &storageClass) {
+			pending = append(pending, pod)
+		}
+	}
+
+	return pending, nil
+}
+
+// readyToMigrate reports whether the next pod can be moved, which is when the StatefulSet creates PVCs with
+// the new StorageClass and all pods, including the last one moved, are ready.
+func (b *StorageMigrationReconciler) readyToMigrate(ctx context.Context, storageClass string) (bool, error) {
+	if b.upgrading() {
+		b.Logger.Info("Waiting for the image upgrade to complete before migrating storage")
+		return false, nil
+	}
+
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      b.Instance.Name,
+			Namespace: b.Instance.Namespace,
+		},
+	}
+	if err := b.GetItem(ctx, sts); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if sts.DeletionTimestamp != nil || !slices.ContainsFunc(sts.Spec.VolumeClaimTemplates, func(pvc corev1.PersistentVolumeClaim) bool {
+		return pvc.Name == "data" && ptr.Deref(pvc.Spec.StorageClassName, "") == storageClass
+	}) {
+		b.Logger.Info("Waiting for the StatefulSet volume claim template to use the new StorageClass", "storageClass", storageClass)
+		return false, nil
+	}
+
+	for i := range int(b.Instance.Spec.Replicas) {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%d", b.Instance.Name, i),
+				Namespace: b.Instance.Namespace,
+			},
+		}
+		if err := b.GetItem(ctx, pod); err != nil {
+			if apierrors.IsNotFound(err) {
+				b.Logger.Info("Waiting for pod to be created", "pod", pod.Name)
+				return false, nil
+			}
+			return false, err
+		}
+
+		if pod.DeletionTimestamp != nil || !podReady(pod) {
+			b.Logger.Info("Waiting for pod to be ready", "pod", pod.Name)
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// migratePod deletes the pod with its PVC. The PVC is kept by Kubernetes until the pod using it is gone, the
+// StatefulSet then creates a new pod and PVC.
+func (b *StorageMigrationReconciler) migratePod(ctx context.Context, name string) error {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "data-" + name,
+			Namespace: b.Instance.Namespace,
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: b.Instance.Namespace,
+		},
+	}
+
+	b.Logger.Info("Moving pod to a new volume", "pod", name)
+	for _, obj := range []client.Object{pvc, pod} {
+		if err := b.Client.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			b.Logger.Error(err, "Failed to delete resource", "name", obj.GetName())
+			return err
+		}
+	}
+
+	b.normalEventf(EventReasonStorageMigration, "Moving pod %s to StorageClass %s, its data is resynced from the leader",
+		name, ptr.Deref(b.Instance.Spec.Persistence.DataVolumeClaimSpec.StorageClassName, ""))
+	return nil
+}
+
+// storageClassChanged reports whether desired names another StorageClass than current. An unset StorageClass
+// is not a change, the cluster default is used for it.
+func storageClassChanged(current, desired *string) bool {
+	return ptr.Deref(current, "") != "" && ptr.Deref(desired, "") != "" && *current != *desired
+}
+
+func podReady(pod *corev1.Pod) bool {
+	return slices.ContainsFunc(pod.Status.Conditions, func(c corev1.PodCondition) bool {
+		return c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue
+	})
+}
+
+// Name returns the name of the storage migration reconciler
+func (b *StorageMigrationReconciler) Name() string {
+	return "storage-migration"
+}
+
+// DependsOn returns the reconcilers that must succeed first, the StatefulSet must create PVCs with the new
+// StorageClass before pods are moved.
+func (b *StorageMigrationReconciler) DependsOn() []string {
+	return []string{b.PVCReconciler().Name(), b.StatefulSetReconciler().Name()}
+}
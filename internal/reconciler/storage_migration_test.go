@@ -0,0 +1,108 @@
+package reconciler_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+	"github.com/cloudamqp/lavinmq-operator/internal/reconciler"
+	testutils "github.com/cloudamqp/lavinmq-operator/internal/test_utils"
+)
+
+type staticLeader string
+
+func (l staticLeader) Leader(context.Context, *cloudamqpcomv1beta1.LavinMQ) (string, error) {
+	return string(l), nil
+}
+
+func TestStorageMigration(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{Replicas: ptr.To(int32(3))})
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+	instance.Spec.Persistence.DataVolumeClaimSpec.StorageClassName = ptr.To("fast")
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	recorder := record.NewFakeRecorder(10)
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+		Recorder: recorder,
+		Leaders:  staticLeader(instance.Name + "-0"),
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	_, err = resourceReconciler.StatefulSetReconciler().Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile statefulset")
+
+	t.Log("Creating the pods and their PVCs on the old StorageClass")
+	for i := range 3 {
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("data-%s-%d", instance.Name, i), Namespace: instance.Namespace},
+			Spec:       *instance.Spec.Persistence.DataVolumeClaimSpec.DeepCopy(),
+		}
+		pvc.Spec.StorageClassName = ptr.To("standard")
+		assert.NoError(t, k8sClient.Create(t.Context(), pvc))
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", instance.Name, i), Namespace: instance.Namespace},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "lavinmq", Image: instance.Spec.Image}}},
+		}
+		assert.NoError(t, k8sClient.Create(t.Context(), pod))
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		assert.NoError(t, k8sClient.Status().Update(t.Context(), pod))
+	}
+
+	rc := resourceReconciler.StorageMigrationReconciler()
+	result, err := rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile storage migration")
+	assert.NotZero(t, result.RequeueAfter)
+
+	t.Log("Moving a follower first")
+	progress := instance.Status.StorageMigration
+	assert.NotNil(t, progress)
+	assert.Equal(t, "fast", progress.StorageClassName)
+	assert.Equal(t, int32(0), progress.Migrated)
+	assert.Equal(t, int32(3), progress.Total)
+	assert.Equal(t, instance.Name+"-1", progress.CurrentPod)
+
+	pvcDeleted := func(ordinal int) bool {
+		pvc := &corev1.PersistentVolumeClaim{}
+		key := types.NamespacedName{Name: fmt.Sprintf("data-%s-%d", instance.Name, ordinal), Namespace: instance.Namespace}
+		err := k8sClient.Get(t.Context(), key, pvc)
+		return err != nil || pvc.DeletionTimestamp != nil
+	}
+	assert.True(t, pvcDeleted(1))
+	assert.False(t, pvcDeleted(0))
+	assert.False(t, pvcDeleted(2))
+
+	pod := &corev1.Pod{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name + "-1", Namespace: instance.Namespace}, pod)
+	assert.True(t, err != nil || pod.DeletionTimestamp != nil, "Pod should be deleted")
+
+	t.Log("Waiting for the moved pod before continuing")
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile storage migration")
+	assert.Equal(t, instance.Name+"-1", instance.Status.StorageMigration.CurrentPod)
+	assert.False(t, pvcDeleted(2))
+
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Normal StorageMigration Moving pod "+instance.Name+"-1 to StorageClass fast")
+}
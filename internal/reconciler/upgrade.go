@@ -0,0 +1,260 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// ConditionUpgradeBlocked is true while spec.image is a downgrade or skips a major version, and the upgrade waits
+// for the ForceAnnotation.
+const ConditionUpgradeBlocked = "UpgradeBlocked"
+
+// How often to check on the pods while a new image is rolled out.
+const upgradeRequeueDelay = 10 * time.Second
+
+// Restarts of a container running the new image after which the upgrade is considered failed.
+const upgradeFailureRestarts = 3
+
+// Reasons for a waiting container that fail an upgrade, the image can't be pulled or LavinMQ keeps crashing.
+var upgradeFailureReasons = []string{"ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CrashLoopBackOff"}
+
+// UpgradeReconciler rolls out a new image. The pods of a cluster are upgraded one at a time, followers first and
+// the leader last, each once all pods are ready, while the StatefulSet uses the OnDelete update strategy. Single
+// nodes are rolled by the StatefulSet itself. When a pod fails to start with the new image the upgrade is rolled
+// back to the image all pods ran before. Risky upgrades, rejected by the webhook, are also held back here for
+// instances the webhook didn't validate, until the ForceAnnotation is set.
+type UpgradeReconciler struct {
+	*ResourceReconciler
+}
+
+func (reconciler *ResourceReconciler) UpgradeReconciler() *UpgradeReconciler {
+	return &UpgradeReconciler{
+		ResourceReconciler: reconciler,
+	}
+}
+
+func (b *UpgradeReconciler) Reconcile(ctx context.Context) (ctrl.Result, error) {
+	if b.Instance.Spec.Maintenance {
+		b.Logger.Info("Instance in maintenance, not upgrading")
+		return ctrl.Result{}, nil
+	}
+
+	status := &b.Instance.Status
+	image := b.deployedImage()
+
+	if status.CurrentImage == "" {
+		// Instances created before upgrades were tracked are assumed to run the image of their spec.
+		b.setCurrentImage(image)
+		return ctrl.Result{}, nil
+	}
+
+	b.reportBlockedUpgrade()
+
+	if image == status.CurrentImage {
+		status.TargetVersion = ""
+		return b.replaceFailedPods(ctx, image)
+	}
+	status.TargetVersion = imageVersion(image)
+
+	pods, err := b.pods(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for _, pod := range pods {
+		if pod != nil && podImage(pod) == image {
+			if reason := startupFailure(pod); reason != "" {
+				b.rollback(pod.Name, reason)
+				return ctrl.Result{RequeueAfter: upgradeRequeueDelay}, nil
+			}
+		}
+	}
+
+	pending := []string{}
+	for _, pod := range pods {
+		if pod == nil || pod.DeletionTimestamp != nil || !podReady(pod) {
+			b.Logger.Info("Waiting for pods to be ready before continuing the upgrade", "image", image)
+			return ctrl.Result{RequeueAfter: upgradeRequeueDelay}, nil
+		}
+		if podImage(pod) != image {
+			pending = append(pending, pod.Name)
+		}
+	}
+
+	if len(pending) == 0 {
+		b.Logger.Info("Upgrade completed", "image", image)
+		b.normalEventf(EventReasonUpgradeCompleted, "Upgraded all pods to %s", image)
+		b.setCurrentImage(image)
+		return ctrl.Result{}, nil
+	}
+
+	if !b.orderedUpgrade() {
+		// The StatefulSet rolls the pods itself.
+		return ctrl.Result{RequeueAfter: upgradeRequeueDelay}, nil
+	}
+
+	pod, err := b.nextPodLeaderLast(ctx, pending)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := b.deletePod(ctx, pod); err != nil {
+		return ctrl.Result{}, err
+	}
+	b.normalEventf(EventReasonUpgrade, "Upgrading pod %s from %s to %s", pod, status.CurrentImage, image)
+
+	return ctrl.Result{RequeueAfter: upgradeRequeueDelay}, nil
+}
+
+// reportBlockedUpgrade sets the UpgradeBlocked condition, with a warning when the upgrade gets blocked.
+func (b *UpgradeReconciler) reportBlockedUpgrade() {
+	status := &b.Instance.Status
+	reason := b.upgradeBlocked()
+	if reason == "" {
+		meta.RemoveStatusCondition(&status.Conditions, ConditionUpgradeBlocked)
+		return
+	}
+
+	changed := meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               ConditionUpgradeBlocked,
+		Status:             metav1.ConditionTrue,
+		Reason:             EventReasonUpgradeBlocked,
+		Message:            reason,
+		ObservedGeneration: b.Instance.Generation,
+	})
+	if changed {
+		b.Logger.Info("Upgrade blocked", "image", b.Instance.Spec.Image, "currentImage", status.CurrentImage, "reason", reason)
+		b.warningEventf(EventReasonUpgradeBlocked, "Not upgrading from %s to %s, %s, set the annotation %s: \"true\" to allow it",
+			status.CurrentImage, b.Instance.Spec.Image, reason, cloudamqpcomv1beta1.ForceAnnotation)
+	}
+}
+
+// rollback gives up on the upgrade to spec.image, the pods return to the current image.
+func (b *UpgradeReconciler) rollback(pod, reason string) {
+	status := &b.Instance.Status
+	b.Logger.Info("Pod failed to start with the new image, rolling back", "pod", pod, "reason", reason,
+		"image", b.Instance.Spec.Image, "currentImage", status.CurrentImage)
+	b.warningEventf(EventReasonUpgradeRolledBack, "Pod %s failed to start with %s (%s), rolling back to %s",
+		pod, b.Instance.Spec.Image, reason, status.CurrentImage)
+	status.RolledBackImage = b.Instance.Spec.Image
+	status.TargetVersion = ""
+}
+
+// replaceFailedPods deletes the pods that failed to start with another image than the current one. The
+// StatefulSet doesn't replace pods that never become ready, so a rollback would be stuck on them.
+func (b *UpgradeReconciler) replaceFailedPods(ctx context.Context, image string) (ctrl.Result, error) {
+	pods, err := b.pods(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for _, pod := range pods {
+		if pod == nil || pod.DeletionTimestamp != nil || podImage(pod) == image || startupFailure(pod) == "" {
+			continue
+		}
+
+		b.Logger.Info("Replacing pod that failed to start with the rolled back image", "pod", pod.Name, "image", podImage(pod))
+		if err := b.deletePod(ctx, pod.Name); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (b *UpgradeReconciler) setCurrentImage(image string) {
+	status := &b.Instance.Status
+	status.CurrentImage = image
+	status.CurrentVersion = imageVersion(image)
+	status.TargetVersion = ""
+	if image == b.Instance.Spec.Image {
+		status.RolledBackImage = ""
+	}
+}
+
+// deployedImage returns the image the pods should run, which is the current image while spec.image is an upgrade
+// that was rolled back or is blocked.
+func (reconciler *ResourceReconciler) deployedImage() string {
+	status := &reconciler.Instance.Status
+	if status.CurrentImage != "" && (status.RolledBackImage == reconciler.Instance.Spec.Image || reconciler.upgradeBlocked() != "") {
+		return status.CurrentImage
+	}
+
+	return reconciler.Instance.Spec.Image
+}
+
+// upgradeBlocked returns why the upgrade from the current image to spec.image is held back, empty if it isn't.
+func (reconciler *ResourceReconciler) upgradeBlocked() string {
+	if reconciler.Instance.Annotations[cloudamqpcomv1beta1.ForceAnnotation] == "true" {
+		return ""
+	}
+
+	return cloudamqpcomv1beta1.UpgradeGate(reconciler.Instance.Status.CurrentImage, reconciler.Instance.Spec.Image)
+}
+
+// upgrading reports whether the pods are being moved to another image than they all run.
+func (reconciler *ResourceReconciler) upgrading() bool {
+	return reconciler.Instance.Status.CurrentImage != "" && reconciler.deployedImage() != reconciler.Instance.Status.CurrentImage
+}
+
+// orderedUpgrade reports whether the pods are upgraded one at a time by the operator, leader last, instead of
+// by the StatefulSet.
+func (reconciler *ResourceReconciler) orderedUpgrade() bool {
+	return reconciler.upgrading() && reconciler.Instance.Spec.Clustering.Enabled() && reconciler.Instance.Spec.Replicas > 1
+}
+
+// startupFailure returns why the LavinMQ container of pod fails to start, empty if it doesn't.
+func startupFailure(pod *corev1.Pod) string {
+	for _, container := range pod.Status.ContainerStatuses {
+		if container.Name != "lavinmq" {
+			continue
+		}
+		if container.State.Waiting != nil && slices.Contains(upgradeFailureReasons, container.State.Waiting.Reason) {
+			return container.State.Waiting.Reason
+		}
+		if container.RestartCount >= upgradeFailureRestarts {
+			return fmt.Sprintf("restarted %d times", container.RestartCount)
+		}
+	}
+
+	return ""
+}
+
+func podImage(pod *corev1.Pod) string {
+	index := slices.IndexFunc(pod.Spec.Containers, func(c corev1.Container) bool { return c.Name == "lavinmq" })
+	if index == -1 {
+		return ""
+	}
+
+	return pod.Spec.Containers[index].Image
+}
+
+// imageVersion returns the LavinMQ version of image, empty if its tag isn't a version.
+func imageVersion(image string) string {
+	version, err := cloudamqpcomv1beta1.ImageVersion(image)
+	if err != nil {
+		return ""
+	}
+
+	return version.String()
+}
+
+// Name returns the name of the upgrade reconciler
+func (b *UpgradeReconciler) Name() string {
+	return "upgrade"
+}
+
+// DependsOn returns the reconcilers that must succeed first, the StatefulSet must create pods with the new image
+// before the old ones are deleted.
+func (b *UpgradeReconciler) DependsOn() []string {
+	return []string{b.StatefulSetReconciler().Name()}
+}
@@ -0,0 +1,255 @@
+package reconciler_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
+	"github.com/cloudamqp/lavinmq-operator/internal/reconciler"
+	testutils "github.com/cloudamqp/lavinmq-operator/internal/test_utils"
+)
+
+func TestUpgradeFollowersFirst(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{Replicas: ptr.To(int32(3))})
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	recorder := record.NewFakeRecorder(10)
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+		Recorder: recorder,
+		Leaders:  staticLeader(instance.Name + "-2"),
+	}
+
+	instance.Status.CurrentImage = "cloudamqp/lavinmq:2.3.0"
+	instance.Spec.Image = "cloudamqp/lavinmq:2.4.1"
+	for i := range 3 {
+		createPod(t, instance, i, instance.Status.CurrentImage, true)
+	}
+
+	rc := resourceReconciler.UpgradeReconciler()
+	result, err := rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile upgrade")
+	assert.NotZero(t, result.RequeueAfter)
+	assert.Equal(t, "2.4.1", instance.Status.TargetVersion)
+
+	t.Log("Upgrading a follower first")
+	podDeleted := func(ordinal int) bool {
+		pod := &corev1.Pod{}
+		key := types.NamespacedName{Name: fmt.Sprintf("%s-%d", instance.Name, ordinal), Namespace: instance.Namespace}
+		err := k8sClient.Get(t.Context(), key, pod)
+		return err != nil || pod.DeletionTimestamp != nil
+	}
+	assert.True(t, podDeleted(0))
+	assert.False(t, podDeleted(1))
+	assert.False(t, podDeleted(2))
+	assert.Contains(t, <-recorder.Events, "Normal Upgrade Upgrading pod "+instance.Name+"-0 from cloudamqp/lavinmq:2.3.0 to cloudamqp/lavinmq:2.4.1")
+
+	t.Log("Completing once all pods run the new image")
+	for i := range 3 {
+		deletePod(t, instance, i)
+		createPod(t, instance, i, instance.Spec.Image, true)
+	}
+
+	result, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile upgrade")
+	assert.Zero(t, result.RequeueAfter)
+	assert.Equal(t, "cloudamqp/lavinmq:2.4.1", instance.Status.CurrentImage)
+	assert.Equal(t, "2.4.1", instance.Status.CurrentVersion)
+	assert.Empty(t, instance.Status.TargetVersion)
+	assert.Contains(t, <-recorder.Events, "Normal UpgradeCompleted Upgraded all pods to cloudamqp/lavinmq:2.4.1")
+}
+
+func TestUpgradeRollback(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{})
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	recorder := record.NewFakeRecorder(10)
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+		Recorder: recorder,
+	}
+
+	instance.Status.CurrentImage = "cloudamqp/lavinmq:2.3.0"
+	instance.Spec.Image = "cloudamqp/lavinmq:2.4.1"
+	pod := createPod(t, instance, 0, instance.Spec.Image, false)
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:         "lavinmq",
+		RestartCount: 3,
+		State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+	}}
+	assert.NoError(t, k8sClient.Status().Update(t.Context(), pod))
+
+	rc := resourceReconciler.UpgradeReconciler()
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile upgrade")
+	assert.Equal(t, "cloudamqp/lavinmq:2.4.1", instance.Status.RolledBackImage)
+	assert.Empty(t, instance.Status.TargetVersion)
+	assert.Contains(t, <-recorder.Events, "Warning UpgradeRolledBack Pod "+instance.Name+"-0 failed to start with cloudamqp/lavinmq:2.4.1 (CrashLoopBackOff)")
+
+	t.Log("Deploying the previous image again and replacing the failed pod")
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+	_, err = resourceReconciler.StatefulSetReconciler().Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile statefulset")
+
+	sts := &appsv1.StatefulSet{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+	assert.Equal(t, "cloudamqp/lavinmq:2.3.0", sts.Spec.Template.Spec.Containers[0].Image)
+
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile upgrade")
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, pod)
+	assert.True(t, err != nil || pod.DeletionTimestamp != nil, "Failed pod should be deleted")
+}
+
+func TestUpgradeBlocked(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{Replicas: ptr.To(int32(3))})
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	recorder := record.NewFakeRecorder(10)
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+		Recorder: recorder,
+		Leaders:  staticLeader(instance.Name + "-2"),
+	}
+
+	instance.Status.CurrentImage = "cloudamqp/lavinmq:2.4.1"
+	instance.Spec.Image = "cloudamqp/lavinmq:2.3.0"
+	for i := range 3 {
+		createPod(t, instance, i, instance.Status.CurrentImage, true)
+	}
+	podDeleted := func(ordinal int) bool {
+		pod := &corev1.Pod{}
+		key := types.NamespacedName{Name: fmt.Sprintf("%s-%d", instance.Name, ordinal), Namespace: instance.Namespace}
+		err := k8sClient.Get(t.Context(), key, pod)
+		return err != nil || pod.DeletionTimestamp != nil
+	}
+
+	rc := resourceReconciler.UpgradeReconciler()
+	for range 2 {
+		result, err := rc.Reconcile(t.Context())
+		assert.NoErrorf(t, err, "Failed to reconcile upgrade")
+		assert.Zero(t, result.RequeueAfter)
+	}
+	for i := range 3 {
+		assert.False(t, podDeleted(i), "A downgrade must not delete pods")
+	}
+	assert.True(t, meta.IsStatusConditionTrue(instance.Status.Conditions, reconciler.ConditionUpgradeBlocked))
+	assert.Contains(t, <-recorder.Events, "Warning UpgradeBlocked Not upgrading from cloudamqp/lavinmq:2.4.1 to cloudamqp/lavinmq:2.3.0, downgrading")
+	assert.Empty(t, recorder.Events, "The warning is recorded once")
+
+	t.Log("Downgrading with the force annotation")
+	instance.Annotations = map[string]string{cloudamqpcomv1beta1.ForceAnnotation: "true"}
+	result, err := rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile upgrade")
+	assert.NotZero(t, result.RequeueAfter)
+	assert.Nil(t, meta.FindStatusCondition(instance.Status.Conditions, reconciler.ConditionUpgradeBlocked))
+	assert.True(t, podDeleted(0))
+	assert.Contains(t, <-recorder.Events, "Normal Upgrade Upgrading pod "+instance.Name+"-0 from cloudamqp/lavinmq:2.4.1 to cloudamqp/lavinmq:2.3.0")
+}
+
+func TestUpgradeOnDeleteStrategy(t *testing.T) {
+	t.Parallel()
+	instance := testutils.GetDefaultInstance(&testutils.DefaultInstanceSettings{Replicas: ptr.To(int32(3))})
+	instance.Spec.Clustering.EtcdEndpoints = []string{"etcd-0:2379"}
+
+	err := testutils.CreateNamespace(t.Context(), k8sClient, instance.Namespace)
+	assert.NoErrorf(t, err, "Failed to create namespace")
+	defer testutils.DeleteNamespace(t.Context(), k8sClient, instance.Namespace)
+
+	configMap := createConfigMap(t, instance, "initial_config")
+	defer deleteConfigMap(t, configMap)
+
+	resourceReconciler := &reconciler.ResourceReconciler{
+		Instance: instance,
+		Scheme:   scheme.Scheme,
+		Client:   k8sClient,
+	}
+
+	err = k8sClient.Create(t.Context(), instance)
+	assert.NoErrorf(t, err, "Failed to create instance")
+
+	rc := resourceReconciler.StatefulSetReconciler()
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile statefulset")
+
+	t.Log("Handing the rollout to the operator while upgrading")
+	instance.Status.CurrentImage = instance.Spec.Image
+	instance.Spec.Image = "cloudamqp/lavinmq:2.5.0"
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile statefulset")
+
+	sts := &appsv1.StatefulSet{}
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+	assert.Equal(t, "OnDelete", string(sts.Spec.UpdateStrategy.Type))
+	assert.Nil(t, sts.Spec.UpdateStrategy.RollingUpdate)
+	assert.Equal(t, "cloudamqp/lavinmq:2.5.0", sts.Spec.Template.Spec.Containers[0].Image)
+
+	t.Log("Rolling the pods with the StatefulSet again once upgraded")
+	instance.Status.CurrentImage = instance.Spec.Image
+	_, err = rc.Reconcile(t.Context())
+	assert.NoErrorf(t, err, "Failed to reconcile statefulset")
+
+	err = k8sClient.Get(t.Context(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, sts)
+	assert.NoErrorf(t, err, "Failed to get statefulset")
+	assert.Equal(t, "RollingUpdate", string(sts.Spec.UpdateStrategy.Type))
+}
+
+func createPod(t *testing.T, instance *cloudamqpcomv1beta1.LavinMQ, ordinal int, image string, ready bool) *corev1.Pod {
+	t.Helper()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", instance.Name, ordinal), Namespace: instance.Namespace},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "lavinmq", Image: image}}},
+	}
+	assert.NoError(t, k8sClient.Create(t.Context(), pod))
+	if ready {
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		assert.NoError(t, k8sClient.Status().Update(t.Context(), pod))
+	}
+
+	return pod
+}
+
+// deletePod removes the pod right away, there's no kubelet in the test environment to terminate it.
+func deletePod(t *testing.T, instance *cloudamqpcomv1beta1.LavinMQ, ordinal int) {
+	t.Helper()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", instance.Name, ordinal), Namespace: instance.Namespace}}
+	err := k8sClient.Delete(t.Context(), pod, client.GracePeriodSeconds(0))
+	assert.NoError(t, client.IgnoreNotFound(err))
+}
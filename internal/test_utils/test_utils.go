@@ -8,7 +8,7 @@ import (
 	"reflect"
 	"runtime"
 
-	cloudamqpcomv1alpha1 "github.com/cloudamqp/lavinmq-operator/api/v1alpha1"
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -24,7 +24,10 @@ func StartKubeTestEnv() (*envtest.Environment, client.Client) {
 	logf.Log.Info("Setting up test suite")
 
 	testEnv := &envtest.Environment{
-		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "config", "crd", "bases")},
+		CRDDirectoryPaths: []string{
+			filepath.Join("..", "..", "config", "crd", "bases"),
+			filepath.Join("..", "..", "test", "crds"),
+		},
 		ErrorIfCRDPathMissing: true,
 
 		// The BinaryAssetsDirectory is only required if you want to run the tests directly
@@ -44,7 +47,7 @@ func StartKubeTestEnv() (*envtest.Environment, client.Client) {
 		os.Exit(1)
 	}
 
-	err = cloudamqpcomv1alpha1.AddToScheme(scheme.Scheme)
+	err = cloudamqpcomv1beta1.AddToScheme(scheme.Scheme)
 	if err != nil {
 		logf.Log.Error(err, "Failed to add scheme")
 		os.Exit(1)
@@ -69,7 +72,7 @@ type DefaultInstanceSettings struct {
 	Resources corev1.ResourceRequirements
 }
 
-func GetDefaultInstance(settings *DefaultInstanceSettings) *cloudamqpcomv1alpha1.LavinMQ {
+func GetDefaultInstance(settings *DefaultInstanceSettings) *cloudamqpcomv1beta1.LavinMQ {
 	// --- Defaults ---
 	defaultName := envconf.RandomName("name", 10)
 	defaultNamespace := envconf.RandomName("namespace", 15)
@@ -108,19 +111,21 @@ func GetDefaultInstance(settings *DefaultInstanceSettings) *cloudamqpcomv1alpha1
 		defaultResources = settings.Resources
 	}
 
-	return &cloudamqpcomv1alpha1.LavinMQ{
+	return &cloudamqpcomv1beta1.LavinMQ{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      instanceName,
 			Namespace: instanceNamespace,
 		},
-		Spec: cloudamqpcomv1alpha1.LavinMQSpec{
+		Spec: cloudamqpcomv1beta1.LavinMQSpec{
 			Image:     instanceImage,
 			Resources: defaultResources,
-			DataVolumeClaimSpec: corev1.PersistentVolumeClaimSpec{
-				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
-				Resources: corev1.VolumeResourceRequirements{
-					Requests: corev1.ResourceList{
-						corev1.ResourceStorage: resource.MustParse(instanceStorage),
+			Persistence: cloudamqpcomv1beta1.PersistenceSpec{
+				DataVolumeClaimSpec: corev1.PersistentVolumeClaimSpec{
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+					Resources: corev1.VolumeResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceStorage: resource.MustParse(instanceStorage),
+						},
 					},
 				},
 			},
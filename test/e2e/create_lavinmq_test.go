@@ -8,7 +8,7 @@ import (
 	"testing"
 	"time"
 
-	cloudamqpcomv1alpha1 "github.com/cloudamqp/lavinmq-operator/api/v1alpha1"
+	cloudamqpcomv1beta1 "github.com/cloudamqp/lavinmq-operator/api/v1beta1"
 
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
@@ -37,41 +37,45 @@ func TestCreateLavinMQ(t *testing.T) {
 			r, err := resources.New(cfg.Client().RESTConfig())
 			assert.NoError(t, err)
 
-			err = cloudamqpcomv1alpha1.AddToScheme(r.GetScheme())
+			err = cloudamqpcomv1beta1.AddToScheme(r.GetScheme())
 			assert.NoError(t, err)
 
 			t.Logf("Trying to create LavinMQ in namespace %s", namespace)
 			r.WithNamespace(namespace)
 
-			lavinmq := &cloudamqpcomv1alpha1.LavinMQ{
+			lavinmq := &cloudamqpcomv1beta1.LavinMQ{
 				TypeMeta: metav1.TypeMeta{
-					APIVersion: "cloudamqp.com/v1alpha1",
+					APIVersion: "cloudamqp.com/v1beta1",
 					Kind:       "LavinMQ",
 				},
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      instanceName,
 					Namespace: namespace,
 				},
-				Spec: cloudamqpcomv1alpha1.LavinMQSpec{
+				Spec: cloudamqpcomv1beta1.LavinMQSpec{
 					Replicas: 1,
 					Image:    "cloudamqp/lavinmq:2.4.1",
-					EtcdEndpoints: []string{
-						fmt.Sprintf("etcd-cluster-0.etcd-cluster.%s.svc.cluster.local:2379", namespace),
-						fmt.Sprintf("etcd-cluster-1.etcd-cluster.%s.svc.cluster.local:2379", namespace),
-						fmt.Sprintf("etcd-cluster-2.etcd-cluster.%s.svc.cluster.local:2379", namespace),
+					Clustering: cloudamqpcomv1beta1.ClusteringSpec{
+						EtcdEndpoints: []string{
+							fmt.Sprintf("etcd-cluster-0.etcd-cluster.%s.svc.cluster.local:2379", namespace),
+							fmt.Sprintf("etcd-cluster-1.etcd-cluster.%s.svc.cluster.local:2379", namespace),
+							fmt.Sprintf("etcd-cluster-2.etcd-cluster.%s.svc.cluster.local:2379", namespace),
+						},
 					},
-					Config: cloudamqpcomv1alpha1.LavinMQConfig{
-						Main: cloudamqpcomv1alpha1.MainConfig{
+					Config: cloudamqpcomv1beta1.LavinMQConfig{
+						Main: cloudamqpcomv1beta1.MainConfig{
 							ConsumerTimeout: 20000,
 						},
 					},
-					DataVolumeClaimSpec: corev1.PersistentVolumeClaimSpec{
-						AccessModes: []corev1.PersistentVolumeAccessMode{
-							corev1.ReadWriteOnce,
-						},
-						Resources: corev1.VolumeResourceRequirements{
-							Requests: corev1.ResourceList{
-								corev1.ResourceStorage: resource.MustParse("3Gi"),
+					Persistence: cloudamqpcomv1beta1.PersistenceSpec{
+						DataVolumeClaimSpec: corev1.PersistentVolumeClaimSpec{
+							AccessModes: []corev1.PersistentVolumeAccessMode{
+								corev1.ReadWriteOnce,
+							},
+							Resources: corev1.VolumeResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceStorage: resource.MustParse("3Gi"),
+								},
 							},
 						},
 					},
@@ -92,7 +96,7 @@ func TestCreateLavinMQ(t *testing.T) {
 
 			r.WithNamespace(namespace)
 
-			err = cloudamqpcomv1alpha1.AddToScheme(r.GetScheme())
+			err = cloudamqpcomv1beta1.AddToScheme(r.GetScheme())
 			assert.NoError(t, err)
 
 			lavinmqSts := &appsv1.StatefulSet{}